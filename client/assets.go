@@ -0,0 +1,282 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+)
+
+// Possible values for the Kind property of the ManifestEntry struct.
+const (
+	ManifestEntryFile      = "file"
+	ManifestEntryDirectory = "directory"
+)
+
+// A Manifest describes the file tree of a Deployment uploaded from local
+// files.
+//
+// Files are identified by the hash of their content, computed the same way git
+// hashes blobs, so the same file is only ever uploaded once.
+type Manifest struct {
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// A ManifestEntry is either a file or a directory in a Manifest. Files have a
+// GitSHA1 and a Size, directories have nested Entries.
+type ManifestEntry struct {
+	Kind    string                   `json:"kind"`
+	GitSHA1 string                   `json:"gitSha1,omitempty"`
+	Size    int                      `json:"size,omitempty"`
+	Entries map[string]ManifestEntry `json:"entries,omitempty"`
+}
+
+// NewFileEntry returns the ManifestEntry of a file with the given content.
+func NewFileEntry(content []byte) ManifestEntry {
+	return ManifestEntry{
+		Kind:    ManifestEntryFile,
+		GitSHA1: GitSHA1(content),
+		Size:    len(content),
+	}
+}
+
+// Add adds a file entry to the Manifest at the given slash separated path,
+// creating the intermediate directory entries.
+func (m *Manifest) Add(name string, entry ManifestEntry) {
+	if m.Entries == nil {
+		m.Entries = map[string]ManifestEntry{}
+	}
+
+	entries := m.Entries
+	segments := strings.Split(name, "/")
+	for _, dir := range segments[:len(segments)-1] {
+		d, ok := entries[dir]
+		if !ok || d.Kind != ManifestEntryDirectory {
+			d = ManifestEntry{Kind: ManifestEntryDirectory, Entries: map[string]ManifestEntry{}}
+			entries[dir] = d
+		}
+		entries = d.Entries
+	}
+	entries[segments[len(segments)-1]] = entry
+}
+
+// Lookup returns the entry at the given slash separated path.
+func (m Manifest) Lookup(name string) (ManifestEntry, bool) {
+	entries := m.Entries
+	segments := strings.Split(name, "/")
+	for _, dir := range segments[:len(segments)-1] {
+		d, ok := entries[dir]
+		if !ok || d.Kind != ManifestEntryDirectory {
+			return ManifestEntry{}, false
+		}
+		entries = d.Entries
+	}
+	e, ok := entries[segments[len(segments)-1]]
+	return e, ok
+}
+
+// DefaultManifestExcludes are the glob patterns of the files that are never
+// included in a Manifest: dotfiles and dot-directories, such as `.git`,
+// `.terraform` or `.env`, and Terraform state files.
+var DefaultManifestExcludes = []string{
+	"**/.*",
+	"**/*.tfstate",
+	"**/*.tfstate.*",
+}
+
+// ManifestOptions defines the files of a local directory included in a
+// Manifest. Include and Exclude are lists of glob patterns as accepted by
+// MatchGlob, matched against the slash separated path of the files relative
+// to Root. When Include is empty, all the files are included. Exclude, along
+// with DefaultManifestExcludes, takes precedence over Include. A directory
+// matching Exclude is skipped with all its content.
+type ManifestOptions struct {
+	Root    string
+	Include []string
+	Exclude []string
+}
+
+// BuildManifest walks a local directory and returns the Manifest of the files
+// matching the options, along with the local path of every file keyed by its
+// GitSHA1 hash.
+func BuildManifest(opts ManifestOptions) (Manifest, map[string]string, error) {
+	manifest := Manifest{Entries: map[string]ManifestEntry{}}
+	files := map[string]string{}
+	exclude := append(append([]string{}, DefaultManifestExcludes...), opts.Exclude...)
+
+	err := filepath.WalkDir(opts.Root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(opts.Root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if e.IsDir() {
+			if name == "." {
+				return nil
+			}
+			ok, err := matchAnyGlob(exclude, name)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if len(opts.Include) > 0 {
+			ok, err := matchAnyGlob(opts.Include, name)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		if ok, err := matchAnyGlob(exclude, name); err != nil || ok {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		entry := NewFileEntry(content)
+		manifest.Add(name, entry)
+		files[entry.GitSHA1] = p
+		return nil
+	})
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	return manifest, files, nil
+}
+
+// GitSHA1 returns the hex encoded SHA-1 hash of the content as a git blob.
+func GitSHA1(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AssetsRootURL is the URL of the root of the Manifest once deployed. The URL
+// of the entrypoint of a Deployment with assets is relative to it, e.g.
+// `file:///src/main.ts`.
+const AssetsRootURL = "file:///src/"
+
+// NegotiateAssets sends the Manifest of a future Deployment to Deploy and
+// returns the GitSHA1 hashes of the files that must be uploaded, that is the
+// files that Deploy doesn't already have.
+func (c *Client) NegotiateAssets(projectID string, manifest Manifest) ([]string, error) {
+	path := fmt.Sprintf("/api/projects/%s/assets/negotiate", projectID)
+
+	bs, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	err = c.request("POST", path, nil, bytes.NewBuffer(bs), &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// NewDeploymentWithAssetsRequest is the expected request body schema for the
+// NewProjectDeploymentWithAssets function.
+type NewDeploymentWithAssetsRequest struct {
+	URL          string       `json:"url"`
+	ImportMapURL string       `json:"importMapUrl,omitempty"`
+	Production   bool         `json:"production,omitempty"`
+	Manifest     Manifest     `json:"manifest"`
+	EnvVars      EnvVarsPatch `json:"envVars,omitempty"`
+}
+
+// NewProjectDeploymentWithAssets creates a new Deployment for a Project from
+// local files. The manifest in the request describes the file tree of the
+// Deployment and the files param contains the content of the files to upload,
+// keyed by their GitSHA1 hash.
+//
+// Unlike NewProjectDeployment, the source code doesn't need to be publicly
+// available. The EnvVars of the request are applied in the same way.
+func (c *Client) NewProjectDeploymentWithAssets(projectID string, depl NewDeploymentWithAssetsRequest, files map[string][]byte) (Deployment, error) {
+	path := fmt.Sprintf("/api/projects/%s/deployment_with_assets", projectID)
+
+	bs, err := json.Marshal(depl)
+	if err != nil {
+		return Deployment{}, err
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if err := w.WriteField("request", string(bs)); err != nil {
+		return Deployment{}, err
+	}
+	for hash, content := range files {
+		part, err := w.CreateFormFile("file", hash)
+		if err != nil {
+			return Deployment{}, err
+		}
+		if _, err := part.Write(content); err != nil {
+			return Deployment{}, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return Deployment{}, err
+	}
+
+	r, err := c.newRequest("POST", path, nil, body)
+	if err != nil {
+		return Deployment{}, err
+	}
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	res := Deployment{}
+	err = c.do(r, &res)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// NewProjectDeploymentFromManifest creates a new Deployment for a Project from
+// the local files of a Manifest built with BuildManifest. Only the files that
+// Deploy doesn't already have are uploaded.
+func (c *Client) NewProjectDeploymentFromManifest(projectID string, depl NewDeploymentWithAssetsRequest, files map[string]string) (Deployment, error) {
+	needed, err := c.NegotiateAssets(projectID, depl.Manifest)
+	if err != nil {
+		return Deployment{}, err
+	}
+
+	uploads := make(map[string][]byte, len(needed))
+	for _, hash := range needed {
+		p, ok := files[hash]
+		if !ok {
+			return Deployment{}, fmt.Errorf("requested asset %s is not in the manifest", hash)
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return Deployment{}, err
+		}
+		if GitSHA1(content) != hash {
+			return Deployment{}, fmt.Errorf("file %s changed while deploying", p)
+		}
+		uploads[hash] = content
+	}
+
+	return c.NewProjectDeploymentWithAssets(projectID, depl, uploads)
+}
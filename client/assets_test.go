@@ -0,0 +1,129 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitSHA1(t *testing.T) {
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"", "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"hello world\n", "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"},
+	}
+
+	for _, c := range cases {
+		if actual := GitSHA1([]byte(c.content)); actual != c.expected {
+			t.Errorf("GitSHA1(%q): expected %s, got %s", c.content, c.expected, actual)
+		}
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.ts":              "import './routes/index.ts';",
+		"routes/index.ts":      "export {};",
+		"routes/index_test.ts": "Deno.test('index', () => {});",
+		"static/style.css":     "body {}",
+		"README.md":            "# app",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest, paths, err := BuildManifest(ManifestOptions{
+		Root:    root,
+		Include: []string{"**/*.ts", "static/**"},
+		Exclude: []string{"**/*_test.ts"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, name := range []string{"main.ts", "routes/index.ts", "static/style.css"} {
+		entry, ok := manifest.Lookup(name)
+		if !ok {
+			t.Errorf("expected %s to be in the manifest", name)
+			continue
+		}
+		if entry.Kind != ManifestEntryFile || entry.GitSHA1 != GitSHA1([]byte(files[name])) || entry.Size != len(files[name]) {
+			t.Errorf("unexpected entry for %s: %+v", name, entry)
+		}
+		if p := paths[entry.GitSHA1]; p != filepath.Join(root, filepath.FromSlash(name)) {
+			t.Errorf("unexpected path for %s: %s", name, p)
+		}
+	}
+
+	for _, name := range []string{"routes/index_test.ts", "README.md"} {
+		if _, ok := manifest.Lookup(name); ok {
+			t.Errorf("expected %s to be excluded from the manifest", name)
+		}
+	}
+
+	if routes, ok := manifest.Lookup("routes"); !ok || routes.Kind != ManifestEntryDirectory {
+		t.Errorf("expected routes to be a directory entry, got %+v", routes)
+	}
+}
+
+func TestBuildManifest_excludes(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.ts":                   "export {};",
+		"vendor/lib.ts":             "export {};",
+		".env":                      "API_KEY=hunter2",
+		".git/config":               "[core]",
+		".terraform/providers.json": "{}",
+		"routes/.secret.ts":         "export {};",
+		"terraform.tfstate":         "{}",
+		"terraform.tfstate.backup":  "{}",
+		"infra/prod.tfstate":        "{}",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// `vendor` only matches the directory itself, its files are excluded
+	// because the directory is skipped
+	manifest, _, err := BuildManifest(ManifestOptions{
+		Root:    root,
+		Exclude: []string{"vendor"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := manifest.Lookup("main.ts"); !ok {
+		t.Errorf("expected main.ts to be in the manifest")
+	}
+	for name := range files {
+		if name == "main.ts" {
+			continue
+		}
+		if _, ok := manifest.Lookup(name); ok {
+			t.Errorf("expected %s to be excluded from the manifest", name)
+		}
+	}
+	for _, dir := range []string{"vendor", ".git", ".terraform", "infra"} {
+		if _, ok := manifest.Lookup(dir); ok {
+			t.Errorf("expected no entry for the %s directory", dir)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import "sync"
+
+// flightGroup de-duplicates identical requests sent concurrently: while a
+// request is in flight, the same request sent again waits for its response
+// instead of being sent a second time.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg   sync.WaitGroup
+	body []byte
+	err  error
+}
+
+// do calls fn and returns its results, unless a call with the same key is
+// already in flight, in which case it waits for it and returns its results.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.body, call.err
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.body, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return call.body, call.err
+}
+
+// domainCache holds the custom domains of each project, as returned by
+// ListDomains, so that reading many domains of the same project only takes a
+// single request.
+type domainCache struct {
+	mu       sync.Mutex
+	projects map[string][]Domain
+	// generations counts the invalidations of each project, so that a list
+	// fetched while the project's domains were modified isn't cached.
+	generations map[string]int
+}
+
+// get returns the cached domains of the project.
+func (dc *domainCache) get(projectID string) ([]Domain, int, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	domains, ok := dc.projects[projectID]
+	return domains, dc.generations[projectID], ok
+}
+
+// set caches the domains of the project, unless it was invalidated since the
+// generation the domains were fetched at.
+func (dc *domainCache) set(projectID string, generation int, domains []Domain) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.generations[projectID] != generation {
+		return
+	}
+	if dc.projects == nil {
+		dc.projects = map[string][]Domain{}
+	}
+	dc.projects[projectID] = domains
+}
+
+// invalidate drops the cached domains of the project.
+func (dc *domainCache) invalidate(projectID string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.generations == nil {
+		dc.generations = map[string]int{}
+	}
+	dc.generations[projectID]++
+	delete(dc.projects, projectID)
+}
+
+// cachedDomain returns the domain from the cached domains of the project,
+// listing them first if they aren't cached yet. It returns false if the domain
+// isn't in the list, in which case it must be requested on its own.
+func (c *Client) cachedDomain(projectID, domainName string) (Domain, bool, error) {
+	domains, generation, ok := c.domains.get(projectID)
+	if !ok {
+		var err error
+		domains, err = c.ListDomains(projectID)
+		if err != nil {
+			return Domain{}, false, err
+		}
+		c.domains.set(projectID, generation, domains)
+	}
+
+	for _, domain := range domains {
+		if domain.Domain == domainName {
+			return domain, true, nil
+		}
+	}
+	return Domain{}, false, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"123","name":"my-project"}`)
+	}))
+	defer srv.Close()
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	defer func() { baseURL = orig }()
+
+	c := New("token")
+	c.Logger = nil
+
+	var wg sync.WaitGroup
+	names := make([]string, 5)
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			project, err := c.GetProject("123")
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			names[i] = project.Name
+		}(i)
+	}
+
+	// give the other callers time to join the request in flight before
+	// releasing it
+	<-started
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single request, got %d", n)
+	}
+	for i, name := range names {
+		if name != "my-project" {
+			t.Errorf("expected caller %d to get the project, got %q", i, name)
+		}
+	}
+
+	// requests sent after the first one completed aren't de-duplicated
+	if _, err := c.GetProject("123"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected a second request, got %d requests", n)
+	}
+}
+
+func TestClientCacheDomains(t *testing.T) {
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/projects/123/domains":
+			fmt.Fprint(w, `[{"domain":"a.example.com","isValidated":true},{"domain":"b.example.com"}]`)
+		case "GET /api/projects/123/domains/c.example.com":
+			fmt.Fprint(w, `{"domain":"c.example.com"}`)
+		case "POST /api/projects/123/domains/b.example.com/verify":
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	defer func() { baseURL = orig }()
+
+	c := New("token")
+	c.Logger = nil
+	c.CacheDomains = true
+
+	for _, name := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		domain, err := c.GetDomain("123", name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if domain.Domain != name {
+			t.Errorf("expected domain %s, got %s", name, domain.Domain)
+		}
+	}
+	if n := requests["GET /api/projects/123/domains"]; n != 1 {
+		t.Errorf("expected the domains to be listed once, got %d", n)
+	}
+
+	// domains missing from the list are requested on their own
+	if domain, err := c.GetDomain("123", "c.example.com"); err != nil || domain.Domain != "c.example.com" {
+		t.Errorf("expected domain c.example.com, got %q and error %v", domain.Domain, err)
+	}
+
+	// modifying a domain invalidates the cache
+	if err := c.VerifyDomain("123", "b.example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.GetDomain("123", "b.example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := requests["GET /api/projects/123/domains"]; n != 2 {
+		t.Errorf("expected the domains to be listed again, got %d", n)
+	}
+
+	// GetDomainUncached always requests the domain
+	if _, err := c.GetDomainUncached("123", "c.example.com"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := requests["GET /api/projects/123/domains/c.example.com"]; n != 2 {
+		t.Errorf("expected the domain to be requested again, got %d", n)
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 )
 
 var (
@@ -20,6 +20,23 @@ var (
 type Client struct {
 	HTTPClient *http.Client
 	Token      string
+	// Logger receives the requests and responses, with their sensitive values
+	// redacted. Defaults to the standard logger.
+	Logger Logger
+	// RateLimiter limits the rate of the requests sent to the API. Requests
+	// aren't limited when it's nil, but requests rejected with a 429 status
+	// are still retried.
+	RateLimiter *RateLimiter
+	// CacheDomains enables a cache of the custom domains of each project for
+	// the lifetime of the Client: GetDomain is served from a single
+	// ListDomains request per project, until the project's domains are
+	// modified through the Client. The changes made outside of the Client,
+	// for instance a domain being validated by Deploy, are not seen until
+	// then; use GetDomainUncached for them.
+	CacheDomains bool
+
+	flights flightGroup
+	domains domainCache
 }
 
 // PageOptions defines the parameters used when requesting a paginated resource.
@@ -37,11 +54,23 @@ type PagingInfo struct {
 	TotalPages int `json:"totalPages"`
 }
 
+// APIError is returned for the responses of the API with an error status.
+type APIError struct {
+	StatusCode int
+	// Body is the body of the response, with its sensitive values redacted.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status: %d, body: %v", e.StatusCode, e.Body)
+}
+
 // New returns a pointer to a new instance of the Deploy sdk
 func New(token string) *Client {
 	return &Client{
 		HTTPClient: http.DefaultClient,
 		Token:      token,
+		Logger:     stdLogger{},
 	}
 }
 
@@ -51,33 +80,108 @@ func (c *Client) request(method, requestPath string, query url.Values, body io.R
 		return err
 	}
 
-	resp, err := c.HTTPClient.Do(r)
-	if err != nil {
-		return err
+	// identical GET requests sent concurrently, for instance when refreshing
+	// many resources of the same project, share the same response
+	if method == "GET" {
+		bodyContents, err := c.flights.do(r.URL.String(), func() ([]byte, error) {
+			return c.fetch(r)
+		})
+		if err != nil || responseStruct == nil {
+			return err
+		}
+		return json.Unmarshal(bodyContents, responseStruct)
 	}
-	defer resp.Body.Close()
 
-	bodyContents, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	return c.do(r, responseStruct)
+}
+
+// do sends the request and decodes the JSON response body into the
+// responseStruct, if it's not nil.
+func (c *Client) do(r *http.Request, responseStruct interface{}) error {
+	bodyContents, err := c.fetch(r)
+	if err != nil || responseStruct == nil {
 		return err
 	}
+	return json.Unmarshal(bodyContents, responseStruct)
+}
+
+// fetch sends the request and returns the response body, or an error if the
+// response status isn't successful. Requests rejected with a 429 status are
+// retried up to maxRateLimitRetries times, once the API accepts requests
+// again. The waits are bounded by maxRateLimitWait.
+func (c *Client) fetch(r *http.Request) ([]byte, error) {
+	var resp *http.Response
+	var bodyContents []byte
+	for attempt := 0; ; attempt++ {
+		var err error
+		resp, bodyContents, err = c.send(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			break
+		}
+		if r.Body != nil {
+			if r.GetBody == nil {
+				break
+			}
+			if r.Body, err = r.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		// the RateLimiter already waits for the API to accept requests again
+		if c.RateLimiter == nil {
+			wait := retryAfter(resp, time.Now())
+			if wait > maxRateLimitWait {
+				return nil, &RateLimitError{Wait: wait}
+			}
+			if err := sleepContext(r.Context(), wait); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("status: %d, body: %v", resp.StatusCode, string(bodyContents))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: RedactBody(r.URL.Path, bodyContents)}
+	}
+
+	return bodyContents, nil
+}
+
+// send sends the request once, waiting for the RateLimiter first, and returns
+// the response along with its body.
+func (c *Client) send(r *http.Request) (*http.Response, []byte, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(r.Context()); err != nil {
+			return nil, nil, err
+		}
 	}
+	c.logRequest(r)
 
-	if responseStruct == nil {
-		return nil
+	resp, err := c.HTTPClient.Do(r)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
-	err = json.Unmarshal(bodyContents, responseStruct)
+	bodyContents, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	c.logResponse(r, resp, bodyContents)
+	if c.RateLimiter != nil {
+		c.RateLimiter.Observe(resp)
 	}
 
-	return nil
+	return resp, bodyContents, nil
 }
 
+// newRequest returns a request to the API authenticated with the token of the
+// Client. The Client isn't context aware: its requests have the background
+// context, so they can't be cancelled and only maxRateLimitWait bounds their
+// rate limit waits.
 func (c *Client) newRequest(method, requestPath string, query url.Values, body io.Reader) (*http.Request, error) {
 	url := *baseURL
 	url.Path = path.Join(url.Path, requestPath)
@@ -92,6 +196,41 @@ func (c *Client) newRequest(method, requestPath string, query url.Values, body i
 	}
 
 	req.Header.Add("Content-Type", "application/json")
-	log.Printf("[DEBUG] deploy sdk doing request %+v", req)
 	return req, err
 }
+
+func (c *Client) logRequest(r *http.Request) {
+	if c.Logger == nil {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"headers": RedactHeaders(r.Header),
+	}
+	// only JSON bodies are logged, not the uploaded files
+	if r.GetBody != nil && r.Header.Get("Content-Type") == "application/json" {
+		if body, err := r.GetBody(); err == nil {
+			bs, _ := ioutil.ReadAll(body)
+			fields["body"] = RedactBody(r.URL.Path, bs)
+		}
+	} else if r.ContentLength > 0 {
+		fields["body_size"] = r.ContentLength
+	}
+	c.Logger.Debug("sending request", fields)
+}
+
+func (c *Client) logResponse(r *http.Request, resp *http.Response, body []byte) {
+	if c.Logger == nil {
+		return
+	}
+
+	c.Logger.Debug("received response", map[string]interface{}{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"status":  resp.StatusCode,
+		"headers": RedactHeaders(resp.Header),
+		"body":    RedactBody(r.URL.Path, body),
+	})
+}
@@ -6,19 +6,44 @@ import (
 	"encoding/json"
 )
 
+// Possible values for the Mode property of the LinkProjectRequest and
+// GitHubLink structs.
+//
+// In automatic mode, Deploy fetches the source code of the repository on every
+// push and optionally runs the install and build commands before deploying it.
+// In GitHub Actions mode, the deployments are uploaded by a workflow running in
+// the repository instead.
+const (
+	GitHubModeAutomatic     = "automatic"
+	GitHubModeGitHubActions = "github_actions"
+)
+
 // LinkProjectRequest is the expected request body schema for the LinkProject
 // function.
+//
+// ProductionBranch defaults to the default branch of the repository when
+// empty. Pushes to other branches create preview deployments, unless
+// PreviewDeployments is false.
 type LinkProjectRequest struct {
-	ProjectID    string `json:"projectId"`
-	Organization string `json:"organization"`
-	Repo         string `json:"repo"`
-	Entrypoint   string `json:"entrypoint"`
+	ProjectID          string `json:"projectId"`
+	Organization       string `json:"organization"`
+	Repo               string `json:"repo"`
+	Entrypoint         string `json:"entrypoint"`
+	ProductionBranch   string `json:"productionBranch,omitempty"`
+	PreviewDeployments bool   `json:"previewDeployments"`
+	Mode               string `json:"mode,omitempty"`
+	InstallCommand     string `json:"installCommand,omitempty"`
+	BuildCommand       string `json:"buildCommand,omitempty"`
 }
 
+// LinkProject links a Project to a GitHub repository. A new Deployment is
+// created every time a commit is pushed to the repository's default branch.
+//
+// Linking a Project that is already linked replaces its existing GitHub link.
 func (c *Client) LinkProject(req LinkProjectRequest) (Project, error) {
 	bs, err := json.Marshal(req)
 	if err != nil {
-		return Project{}, nil
+		return Project{}, err
 	}
 
 	res := Project{}
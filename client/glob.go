@@ -0,0 +1,59 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the slash separated name matches the glob pattern.
+//
+// Patterns use the syntax of path.Match for each path segment, with the
+// addition of `**` which matches zero or more whole segments. For instance
+// `**/*.ts` matches `main.ts` and `routes/api/index.ts`, and `static/**`
+// matches every file under the `static` directory.
+func MatchGlob(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// collapse consecutive `**` segments
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// matchAnyGlob reports whether the name matches at least one of the patterns.
+func matchAnyGlob(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		ok, err := MatchGlob(p, name)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"main.ts", "main.ts", true},
+		{"main.ts", "src/main.ts", false},
+		{"*.ts", "main.ts", true},
+		{"*.ts", "src/main.ts", false},
+		{"**", "main.ts", true},
+		{"**", "src/routes/index.ts", true},
+		{"**/*.ts", "main.ts", true},
+		{"**/*.ts", "src/routes/index.ts", true},
+		{"**/*.ts", "src/routes/index.tsx", false},
+		{"static/**", "static/css/main.css", true},
+		{"static/**", "src/static.ts", false},
+		{"src/**/*_test.ts", "src/main_test.ts", true},
+		{"src/**/*_test.ts", "src/a/b/c_test.ts", true},
+		{"src/**/*_test.ts", "test/main_test.ts", false},
+		{"**/**/*.md", "README.md", true},
+		{"?.ts", "a.ts", true},
+		{"[ab].ts", "c.ts", false},
+	}
+
+	for _, c := range cases {
+		actual, err := MatchGlob(c.pattern, c.name)
+		if err != nil {
+			t.Errorf("MatchGlob(%q, %q): unexpected error: %s", c.pattern, c.name, err)
+			continue
+		}
+		if actual != c.expected {
+			t.Errorf("MatchGlob(%q, %q): expected %t, got %t", c.pattern, c.name, c.expected, actual)
+		}
+	}
+
+	if _, err := MatchGlob("[", "a"); err == nil {
+		t.Errorf("MatchGlob(%q): expected an error for a malformed pattern", "[")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Logger is used by the Client to log the requests it sends and the responses
+// it receives. The fields passed to the Logger are already redacted.
+type Logger interface {
+	Debug(msg string, fields map[string]interface{})
+}
+
+// stdLogger is the default Logger of the Client. It writes to the standard
+// logger with the `[DEBUG]` prefix understood by Terraform.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	log.Printf("[DEBUG] %s:%s", msg, b.String())
+}
+
+// Redacted replaces the sensitive values in the logs.
+const Redacted = "[REDACTED]"
+
+// PrivateKeyRegexp matches PEM encoded private keys.
+var PrivateKeyRegexp = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+// sensitiveHeaders are the HTTP headers never logged in clear.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// sensitiveKeys are the keys of the JSON bodies whose values are never logged
+// in clear, compared case insensitively.
+var sensitiveKeys = map[string]bool{
+	"token":       true,
+	"accesstoken": true,
+	"apitoken":    true,
+	"password":    true,
+	"secret":      true,
+	"privatekey":  true,
+}
+
+// RedactHeaders returns a copy of the headers with the values of the sensitive
+// ones replaced.
+func RedactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if redacted.Get(k) != "" {
+			redacted.Set(k, Redacted)
+		}
+	}
+	return redacted
+}
+
+// RedactBody returns the JSON body of a request to or a response from the
+// given API path with its sensitive values replaced: tokens, environment
+// variable values and private keys. Bodies that aren't valid JSON are only
+// stripped of private keys.
+func RedactBody(apiPath string, body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return PrivateKeyRegexp.ReplaceAllString(string(body), Redacted)
+	}
+
+	// the body of the env endpoints is the map of environment variables itself
+	if strings.HasSuffix(apiPath, "/env") {
+		v = redactValues(v)
+	} else {
+		v = redactJSON(v)
+	}
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return Redacted
+	}
+	return string(bs)
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			switch {
+			case sensitiveKeys[strings.ToLower(k)]:
+				v[k] = Redacted
+			case k == "envVars":
+				// the values of the environment variables are sent when
+				// creating projects, only their keys are ever returned
+				v[k] = redactValues(e)
+			default:
+				v[k] = redactJSON(e)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redactJSON(e)
+		}
+		return v
+	case string:
+		return PrivateKeyRegexp.ReplaceAllString(v, Redacted)
+	default:
+		return v
+	}
+}
+
+// redactValues replaces the non-null values of a JSON object, keeping its keys.
+func redactValues(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, e := range m {
+		if e != nil {
+			m[k] = Redacted
+		}
+	}
+	return m
+}
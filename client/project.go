@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 )
 
@@ -46,11 +45,19 @@ type EnvVars []string
 // A GitHubLink is used in a Project to link it to a GitHub repository. it
 // contains a Repository struct and an entrypoint corresponding to the source
 // code file used as the entrypoint of the project.
+//
+// The other properties mirror the settings of the LinkProjectRequest used to
+// create the link.
 type GitHubLink struct {
-	Repository Repository `json:"repository"`
-	Entrypoint string     `json:"entrypoint"`
-	UpdatedAt  string     `json:"updatedAt"`
-	CreatedAt  string     `json:"createdAt"`
+	Repository         Repository `json:"repository"`
+	Entrypoint         string     `json:"entrypoint"`
+	ProductionBranch   string     `json:"productionBranch"`
+	PreviewDeployments bool       `json:"previewDeployments"`
+	Mode               string     `json:"mode"`
+	InstallCommand     string     `json:"installCommand,omitempty"`
+	BuildCommand       string     `json:"buildCommand,omitempty"`
+	UpdatedAt          string     `json:"updatedAt"`
+	CreatedAt          string     `json:"createdAt"`
 }
 
 // A Repository is a simple structure containing the information identifying the
@@ -70,12 +77,19 @@ type Repository struct {
 // If the project is linked to a GitHub repository, it will also contain a
 // CommitInfo containing the summary of the commit.
 //
+// A Deployment is created in the DeploymentStatusPending status, and moves to
+// either DeploymentStatusSuccess once it is ready to serve requests, or to
+// DeploymentStatusFailed if it failed to boot, in which case Error contains
+// the reason of the failure.
+//
 // A Deployment also has a circular reference to a Project. The Project property
 // is only set when accessing the Deployment directly in the API, otherwise it
 // is omitted.
 type Deployment struct {
 	ID             string          `json:"id"`
 	URL            string          `json:"url"`
+	Status         string          `json:"status"`
+	Error          string          `json:"error,omitempty"`
 	DomainMappings []DomainMapping `json:"domainMappings"`
 	RelatedCommit  *CommitInfo     `json:"relatedCommit,omitempty"`
 	Project        *Project        `json:"project"`
@@ -85,6 +99,13 @@ type Deployment struct {
 	CreatedAt      string          `json:"createdAt"`
 }
 
+// Possible values for the Status property of the Deployment struct
+const (
+	DeploymentStatusPending = "pending"
+	DeploymentStatusSuccess = "success"
+	DeploymentStatusFailed  = "failed"
+)
+
 // A DomainMapping is a simple struct containing to immutable domain name of a
 // Deployment.
 type DomainMapping struct {
@@ -207,6 +228,7 @@ func (c *Client) UpdateProject(projectID string, newName string) error {
 
 // DeleteProject deletes a Project and all of its associated Deployments.
 func (c *Client) DeleteProject(projectID string) error {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s", projectID)
 	return c.request("DELETE", path, nil, nil, nil)
 }
@@ -215,30 +237,30 @@ func (c *Client) DeleteProject(projectID string) error {
 func (c *Client) GetProject(projectID string) (Project, error) {
 	path := fmt.Sprintf("/api/projects/%s", projectID)
 	result := Project{}
-	log.Printf("[DEBUG] GET %s", path)
 	err := c.request("GET", path, nil, nil, &result)
 	if err != nil {
-		log.Printf("[DEBUG] Could not find project: %s", err)
 		return result, err
 	}
 
-	bs, _ := json.Marshal(result)
-	log.Printf("[DEBUG] GET Project response body: %s", string(bs))
-
 	return result, nil
 }
 
 // NewDeploymentRequest is the expected request body schema for the
 // NewProjectDeployment function.
 type NewDeploymentRequest struct {
-	URL        string `json:"url"`
-	Production bool   `json:"production,omitempty"`
+	URL        string       `json:"url"`
+	Production bool         `json:"production,omitempty"`
+	EnvVars    EnvVarsPatch `json:"envVars,omitempty"`
 }
 
 // NewProjectDeployment creates a new Deployment for a Project. The URL param
 // is the URL of the source code used for the deployment. The URL needs to be
 // publicly available.
 //
+// The EnvVars of the request are applied to the environment variables of the
+// Project along with the Deployment, which is created with the updated
+// variables, in the same way as with PatchEnvVars.
+//
 // Note that this function is not needed to create a new Deployment for a
 // Project linked to a GitHub repository.
 func (c *Client) NewProjectDeployment(projectID string, depl NewDeploymentRequest) (Deployment, error) {
@@ -263,7 +285,7 @@ func (c *Client) NewProjectDeployment(projectID string, depl NewDeploymentReques
 func (c *Client) ListDeployments(projectID string, pageOpts PageOptions) ([]Deployment, PagingInfo, error) {
 	path := fmt.Sprintf("/api/projects/%s/deployments", projectID)
 	// expected []Deployment at position 0 and PagingInfo at position 1
-	result := []interface{}{}
+	result := []json.RawMessage{}
 
 	qs := url.Values{}
 	if pageOpts.Page != 0 {
@@ -277,8 +299,20 @@ func (c *Client) ListDeployments(projectID string, pageOpts PageOptions) ([]Depl
 	if err != nil {
 		return []Deployment{}, PagingInfo{}, err
 	}
+	if len(result) != 2 {
+		return []Deployment{}, PagingInfo{}, fmt.Errorf("unexpected response, expected a list of deployments and paging info")
+	}
 
-	return result[0].([]Deployment), result[1].(PagingInfo), nil
+	deployments := []Deployment{}
+	if err := json.Unmarshal(result[0], &deployments); err != nil {
+		return []Deployment{}, PagingInfo{}, err
+	}
+	paging := PagingInfo{}
+	if err := json.Unmarshal(result[1], &paging); err != nil {
+		return []Deployment{}, PagingInfo{}, err
+	}
+
+	return deployments, paging, nil
 }
 
 // GetDeployment returns the information about a given Deployment.
@@ -299,6 +333,9 @@ func (c *Client) GetLogs(projectID string, deploymentID string) (interface{}, er
 }
 
 // UpdateEnvVars overwrites the environment variables of a given Project.
+//
+// Any variable not in newVars is removed, including the ones set concurrently
+// by other writers. Prefer PatchEnvVars to only update some variables.
 func (c *Client) UpdateEnvVars(projectID string, newVars NewEnvVars) error {
 	path := fmt.Sprintf("/api/projects/%s/env", projectID)
 
@@ -310,6 +347,41 @@ func (c *Client) UpdateEnvVars(projectID string, newVars NewEnvVars) error {
 	return c.request("POST", path, nil, bytes.NewBuffer(bs), nil)
 }
 
+// EnvVarsPatch is the expected request body schema for the PatchEnvVars
+// function. Variables with a non-nil value are created or updated, variables
+// with a nil value are removed.
+type EnvVarsPatch map[string]*string
+
+// PatchEnvVars applies a partial update to the environment variables of a
+// given Project. The variables that are not part of the patch are left
+// untouched.
+//
+// The production Deployment of the Project is redeployed with the new
+// variables. To change the variables along with a new Deployment, set the
+// EnvVars of the deployment request instead.
+func (c *Client) PatchEnvVars(projectID string, patch EnvVarsPatch) error {
+	path := fmt.Sprintf("/api/projects/%s/env", projectID)
+
+	bs, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	return c.request("PATCH", path, nil, bytes.NewBuffer(bs), nil)
+}
+
+// SetEnvVar creates or updates a single environment variable of a given
+// Project. The other environment variables of the Project are left untouched.
+func (c *Client) SetEnvVar(projectID, key, value string) error {
+	return c.PatchEnvVars(projectID, EnvVarsPatch{key: &value})
+}
+
+// DeleteEnvVar removes a single environment variable of a given Project. The
+// other environment variables of the Project are left untouched.
+func (c *Client) DeleteEnvVar(projectID, key string) error {
+	return c.PatchEnvVars(projectID, EnvVarsPatch{key: nil})
+}
+
 // Unlink removes the GitHub integration of a given project.
 //
 // This only affects future Deployments. Any active Deployment that was created
@@ -335,6 +407,7 @@ func (c *Client) ListDomains(projectID string) ([]Domain, error) {
 // AddDomain adds a custom domain name to the project. This is typically
 // followed by the VerifyDomain function
 func (c *Client) AddDomain(projectID string, newDomain Domain) (Domain, error) {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s/domains", projectID)
 
 	bs, err := json.Marshal(newDomain)
@@ -355,7 +428,25 @@ func (c *Client) AddDomain(projectID string, newDomain Domain) (Domain, error) {
 //
 // This is typically used to retrieve the information about the different
 // records that must be created by the user to properly verify the domain.
+//
+// When CacheDomains is enabled, the domain is looked up in the cached domains
+// of the project.
 func (c *Client) GetDomain(projectID, domainName string) (Domain, error) {
+	if c.CacheDomains {
+		domain, ok, err := c.cachedDomain(projectID, domainName)
+		if err != nil || ok {
+			return domain, err
+		}
+	}
+
+	return c.GetDomainUncached(projectID, domainName)
+}
+
+// GetDomainUncached returns the custom domain like GetDomain, but always
+// requests it from Deploy, even when CacheDomains is enabled. It is used for
+// the attributes of the domain that change outside of the Client, such as its
+// validation and its certificates.
+func (c *Client) GetDomainUncached(projectID, domainName string) (Domain, error) {
 	path := fmt.Sprintf("/api/projects/%s/domains/%s", projectID, domainName)
 	result := Domain{}
 	err := c.request("GET", path, nil, nil, &result)
@@ -371,6 +462,7 @@ func (c *Client) GetDomain(projectID, domainName string) (Domain, error) {
 // This action only removes the custom domain name resolution on the Deploy side.
 // The DNS records will still have to be removed on the user's registrar.
 func (c *Client) DeleteDomain(projectID, domainName string) error {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s/domains/%s", projectID, domainName)
 	return c.request("DELETE", path, nil, nil, nil)
 }
@@ -379,6 +471,7 @@ func (c *Client) DeleteDomain(projectID, domainName string) error {
 // domain names. The DNS records must exist prior to starting the verification
 // process. Deploy will not create these for you.
 func (c *Client) VerifyDomain(projectID, domainName string) error {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s/domains/%s/verify", projectID, domainName)
 	return c.request("POST", path, nil, nil, nil)
 }
@@ -386,6 +479,7 @@ func (c *Client) VerifyDomain(projectID, domainName string) error {
 // ProvisionCertificateAutomatic automatically provisions a valid TLS
 // certificate for a custom domain name.
 func (c *Client) ProvisionCertificateAutomatic(projectID, domainName string) error {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s/domains/%s/certificates", projectID, domainName)
 	bs, err := json.Marshal(map[string]string{"strategy": "automatic"})
 	if err != nil {
@@ -397,6 +491,7 @@ func (c *Client) ProvisionCertificateAutomatic(projectID, domainName string) err
 // ProvisionCertificateManual adds a custom TLS certificate for a custom domain
 // name.
 func (c *Client) ProvisionCertificateManual(projectID, domainName string, certificateChain string, privateKey string) error {
+	defer c.domains.invalidate(projectID)
 	path := fmt.Sprintf("/api/projects/%s/domains/%s/certificates", projectID, domainName)
 	bs, err := json.Marshal(map[string]string{"strategy": "manual", "certificateChain": certificateChain, "privateKey": privateKey})
 	if err != nil {
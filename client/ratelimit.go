@@ -0,0 +1,220 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitRetries is the number of times a request rejected with a 429
+// status is retried.
+const maxRateLimitRetries = 3
+
+// defaultRetryAfter is how long to wait before retrying a request rejected
+// with a 429 status without a Retry-After header.
+const defaultRetryAfter = time.Second
+
+// maxRateLimitWait is the longest wait before sending a request. A request the
+// API only accepts again later fails with a RateLimitError instead, rather
+// than blocking the Terraform run on a bogus or far away reset.
+const maxRateLimitWait = 2 * time.Minute
+
+// RateLimitError is returned for the requests that would have to wait longer
+// than maxRateLimitWait for the API to accept them.
+type RateLimitError struct {
+	Wait time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by the API for %s, longer than the maximum wait of %s", e.Wait.Round(time.Second), maxRateLimitWait)
+}
+
+// A RateLimiter is a token bucket limiting the rate of the requests sent by a
+// Client. The same RateLimiter is shared by all the resources of a Terraform
+// run through the Client of the provider.
+//
+// The rate limit headers of the responses are fed back into the RateLimiter:
+// when the API rejects a request, or reports that no request is left in the
+// current window, the following requests wait until the API is ready to accept
+// them again.
+type RateLimiter struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+
+	// now and sleep are overridden by the tests.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewRateLimiter returns a RateLimiter allowing requestsPerSecond requests
+// per second on average, and bursts of up to burst requests. When
+// requestsPerSecond is zero, only the rate limit headers are followed.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// Wait blocks until a request can be sent, or until the context is done. It
+// returns a RateLimitError without waiting if the request can't be sent within
+// maxRateLimitWait.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait, err := l.reserve()
+	if err != nil {
+		return err
+	}
+	return l.sleep(ctx, wait)
+}
+
+// reserve takes a token from the bucket and returns how long to wait before
+// using it. The bucket goes into debt when it's empty, so that concurrent
+// requests are spread over time rather than all sent once a token is
+// available. No token is taken when the wait would exceed maxRateLimitWait.
+func (l *RateLimiter) reserve() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	tokens := l.tokens
+	var wait time.Duration
+	// a RateLimiter without rate only follows the rate limit headers
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			tokens += now.Sub(l.last).Seconds() * l.rate
+			if tokens > l.burst {
+				tokens = l.burst
+			}
+		}
+		tokens--
+		if tokens < 0 {
+			wait = time.Duration(-tokens / l.rate * float64(time.Second))
+		}
+	}
+	if blocked := l.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	if wait > maxRateLimitWait {
+		return 0, &RateLimitError{Wait: wait}
+	}
+
+	if l.rate > 0 {
+		l.tokens = tokens
+		l.last = now
+	}
+	return wait, nil
+}
+
+// sleepContext waits for the duration, or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Observe updates the RateLimiter from the rate limit headers of a response:
+// Retry-After, or X-RateLimit-Remaining along with X-RateLimit-Reset.
+func (l *RateLimiter) Observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	until, ok := rateLimitedUntil(resp, now)
+	if ok && until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// rateLimitedUntil returns when the API accepts requests again, if it's
+// rate limiting them.
+func rateLimitedUntil(resp *http.Response, now time.Time) (time.Time, bool) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return now.Add(d), true
+		}
+		if reset, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+			return reset, true
+		}
+		return now.Add(defaultRetryAfter), true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+			return reset, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// retryAfter returns how long to wait before retrying a request rejected with
+// a 429 status.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	until, _ := rateLimitedUntil(resp, now)
+	if d := until.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// maxHeaderSeconds bounds the numbers of seconds of the rate limit headers, so
+// that they don't overflow a time.Duration.
+const maxHeaderSeconds = math.MaxInt64 / int64(time.Second)
+
+// parseRetryAfter parses the Retry-After header, either a number of seconds or
+// an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil && s >= 0 {
+		if s > maxHeaderSeconds {
+			s = maxHeaderSeconds
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// parseRateLimitReset parses the X-RateLimit-Reset header, either a Unix
+// timestamp or a number of seconds until the reset.
+func parseRateLimitReset(v string, now time.Time) (time.Time, bool) {
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil || s < 0 || math.IsNaN(s) {
+		return time.Time{}, false
+	}
+	if s > float64(maxHeaderSeconds) {
+		s = float64(maxHeaderSeconds)
+	}
+	// a number of seconds this large can only be a timestamp
+	if s > 1e9 {
+		sec, frac := math.Modf(s)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+	}
+	return now.Add(time.Duration(s * float64(time.Second))), true
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeClock is the clock of a RateLimiter under test, sleeping advances it.
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func newTestRateLimiter(rps float64, burst int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	l := NewRateLimiter(rps, burst)
+	l.now = func() time.Time { return clock.now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		clock.slept = append(clock.slept, d)
+		clock.now = clock.now.Add(d)
+		return nil
+	}
+	return l, clock
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l, clock := newTestRateLimiter(10, 2)
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	expected := []time.Duration{0, 0, 100 * time.Millisecond, 100 * time.Millisecond}
+	if fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("expected waits %v, got %v", expected, clock.slept)
+	}
+
+	// the bucket refills while idle, up to the burst
+	clock.slept = nil
+	clock.now = clock.now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	expected = []time.Duration{0, 0, 100 * time.Millisecond}
+	if fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("expected waits %v, got %v", expected, clock.slept)
+	}
+}
+
+func TestRateLimiter_Observe(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cases := []struct {
+		name     string
+		status   int
+		headers  map[string]string
+		expected time.Duration
+	}{
+		{"ok", 200, nil, 0},
+		{"remaining", 200, map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "30"}, 0},
+		{"exhausted", 200, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "30"}, 30 * time.Second},
+		{"exhausted timestamp", 200, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1600000010"}, 10 * time.Second},
+		{"retry after", 429, map[string]string{"Retry-After": "3"}, 3 * time.Second},
+		{"retry after date", 429, map[string]string{"Retry-After": now.Add(5 * time.Second).UTC().Format(http.TimeFormat)}, 5 * time.Second},
+		{"too many requests reset", 429, map[string]string{"X-RateLimit-Reset": "7"}, 7 * time.Second},
+		{"too many requests", 429, nil, defaultRetryAfter},
+		{"at the limit", 429, map[string]string{"Retry-After": "120"}, maxRateLimitWait},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l, clock := newTestRateLimiter(0, 1)
+			resp := &http.Response{StatusCode: c.status, Header: http.Header{}}
+			for k, v := range c.headers {
+				resp.Header.Set(k, v)
+			}
+
+			l.Observe(resp)
+			if err := l.Wait(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if clock.slept[0] != c.expected {
+				t.Errorf("expected to wait %s, got %s", c.expected, clock.slept[0])
+			}
+		})
+	}
+}
+
+func TestRateLimiter_maxWait(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"retry after", map[string]string{"Retry-After": "121"}},
+		{"retry after overflow", map[string]string{"Retry-After": "99999999999999999"}},
+		{"retry after date", map[string]string{"Retry-After": "Fri, 31 Dec 2100 23:59:59 GMT"}},
+		{"reset", map[string]string{"X-RateLimit-Reset": "3600"}},
+		{"reset overflow", map[string]string{"X-RateLimit-Reset": "1e300"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l, clock := newTestRateLimiter(0, 1)
+			resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+			for k, v := range c.headers {
+				resp.Header.Set(k, v)
+			}
+
+			l.Observe(resp)
+			var rateLimitErr *RateLimitError
+			if err := l.Wait(context.Background()); !errors.As(err, &rateLimitErr) {
+				t.Fatalf("expected a RateLimitError, got %v", err)
+			}
+			if rateLimitErr.Wait <= maxRateLimitWait {
+				t.Errorf("expected a wait longer than %s, got %s", maxRateLimitWait, rateLimitErr.Wait)
+			}
+			if len(clock.slept) != 0 {
+				t.Errorf("expected not to sleep, slept %v", clock.slept)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_maxWaitKeepsTokens(t *testing.T) {
+	// a token every 1000 seconds: the second request would wait too long, and
+	// is rejected without taking the next token
+	l, clock := newTestRateLimiter(0.001, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := l.Wait(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	clock.now = clock.now.Add(1000 * time.Second)
+	clock.slept = nil
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := []time.Duration{0}; fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("expected waits %v, got %v", expected, clock.slept)
+	}
+}
+
+func TestRateLimiter_context(t *testing.T) {
+	l := NewRateLimiter(0, 1)
+	l.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"60"}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the wait to stop with the context, waited %s", elapsed)
+	}
+}
+
+func TestClientRetriesRateLimitedRequests(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"123","name":"my-project"}`)
+	}))
+	defer srv.Close()
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	defer func() { baseURL = orig }()
+
+	c := New("token")
+	c.Logger = nil
+	c.RateLimiter = NewRateLimiter(100, 1)
+
+	project, err := c.CreateProject("my-project", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if project.ID != "123" || requests != 3 {
+		t.Errorf("expected project 123 after 3 requests, got %q after %d", project.ID, requests)
+	}
+
+	// requests still rejected after the retries return an error
+	requests = -10
+	if _, err := c.CreateProject("my-project", nil); err == nil {
+		t.Errorf("expected an error")
+	}
+	if requests != -10+maxRateLimitRetries+1 {
+		t.Errorf("expected %d requests, got %d", maxRateLimitRetries+1, requests+10)
+	}
+}
+
+func TestClientRateLimitedRequestsMaxWait(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	defer func() { baseURL = orig }()
+
+	// with and without a RateLimiter, the request fails instead of waiting
+	// for an hour
+	for _, limiter := range []*RateLimiter{nil, NewRateLimiter(100, 1)} {
+		requests = 0
+		c := New("token")
+		c.Logger = nil
+		c.RateLimiter = limiter
+
+		_, err := c.CreateProject("my-project", nil)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected a RateLimitError, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("expected a single request, got %d", requests)
+		}
+	}
+}
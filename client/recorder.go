@@ -0,0 +1,207 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RecorderMode defines whether a Recorder records new interactions or replays
+// recorded ones.
+type RecorderMode int
+
+const (
+	// RecorderModeReplay replays the interactions of an existing cassette
+	// without sending any request.
+	RecorderModeReplay RecorderMode = iota
+	// RecorderModeRecord sends the requests and records the interactions to a
+	// new cassette.
+	RecorderModeRecord
+)
+
+// A Cassette is a list of recorded HTTP interactions, stored as a JSON file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// An Interaction is a request and the response it received.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request stored in a Cassette. The headers
+// aren't recorded, so the API token never ends up in a cassette, and the JSON
+// bodies are redacted the same way as the logs of the Client.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response stored in a Cassette.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// recordedHeaders are the response headers kept in cassettes.
+var recordedHeaders = []string{"Content-Type"}
+
+// A Recorder is an http.RoundTripper recording HTTP interactions to a
+// Cassette file, or replaying them, so that tests can run against realistic
+// API responses without a token or network access.
+//
+// When replaying, each request is answered with the first interaction of the
+// cassette with the same method and URL that wasn't replayed yet, so requests
+// made repeatedly, such as the polling of a deployment, get the responses in
+// the order they were recorded.
+type Recorder struct {
+	// Base is the RoundTripper sending the requests in record mode. Defaults
+	// to http.DefaultTransport.
+	Base http.RoundTripper
+
+	mode     RecorderMode
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. In replay mode, the
+// cassette must exist, an error wrapping os.ErrNotExist is returned
+// otherwise.
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{mode: mode, path: path}
+	if mode == RecorderModeRecord {
+		r.cassette.Interactions = []Interaction{}
+		return r, nil
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	if err := json.Unmarshal(bs, &r.cassette); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Mode returns the mode of the Recorder.
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == RecorderModeReplay {
+		return r.replay(req)
+	}
+	return r.record(req)
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.replayed[i] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.String() {
+			continue
+		}
+		r.replayed[i] = true
+
+		res := interaction.Response
+		headers := res.Headers.Clone()
+		if headers == nil {
+			headers = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", res.Status, http.StatusText(res.Status)),
+			StatusCode:    res.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        headers,
+			Body:          ioutil.NopCloser(bytes.NewBufferString(res.Body)),
+			ContentLength: int64(len(res.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no interaction left in cassette %s for %s %s", r.path, req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	recorded := RecordedRequest{Method: req.Method, URL: req.URL.String()}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			bs, _ := ioutil.ReadAll(body)
+			recorded.Body = traceBody(req.URL.Path, req.Header, bs)
+		}
+	}
+
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	if err != nil {
+		return resp, err
+	}
+
+	headers := http.Header{}
+	for _, k := range recordedHeaders {
+		if v := resp.Header.Get(k); v != "" {
+			headers.Set(k, v)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    string(bs),
+		},
+	})
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing
+// in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != RecorderModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bs, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(bs, '\n'), 0644)
+}
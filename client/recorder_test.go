@@ -0,0 +1,105 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/projects":
+			fmt.Fprint(w, `{"id":"123","name":"my-project"}`)
+		case "/api/projects/123/deployments/456":
+			polls++
+			status := DeploymentStatusPending
+			if polls > 1 {
+				status = DeploymentStatusSuccess
+			}
+			fmt.Fprintf(w, `{"id":"456","status":%q}`, status)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	defer func() { baseURL = orig }()
+
+	path := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+
+	// record
+	recorder, err := NewRecorder(path, RecorderModeRecord)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c := New("secret-token")
+	c.Logger = nil
+	c.HTTPClient = &http.Client{Transport: recorder}
+	testRecorderScenario(t, c)
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, secret := range []string{"secret-token", "hunter2"} {
+		if strings.Contains(string(bs), secret) {
+			t.Errorf("expected %q not to be recorded", secret)
+		}
+	}
+
+	// replay, without the server
+	srv.Close()
+	recorder, err = NewRecorder(path, RecorderModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c.HTTPClient = &http.Client{Transport: recorder}
+	testRecorderScenario(t, c)
+
+	// every interaction was replayed
+	if _, err := c.GetDeployment("123", "456"); err == nil {
+		t.Errorf("expected an error when no interaction is left")
+	}
+}
+
+func testRecorderScenario(t *testing.T, c *Client) {
+	project, err := c.CreateProject("my-project", NewEnvVars{"API_KEY": "hunter2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if project.ID != "123" {
+		t.Errorf("expected project 123, got %s", project.ID)
+	}
+
+	for _, expected := range []string{DeploymentStatusPending, DeploymentStatusSuccess} {
+		depl, err := c.GetDeployment("123", "456")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if depl.Status != expected {
+			t.Errorf("expected status %s, got %s", expected, depl.Status)
+		}
+	}
+}
+
+func TestRecorder_missingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), RecorderModeReplay)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A TraceTransport is an http.RoundTripper recording every request sent
+// through it, along with its response, to a trace file. The headers and bodies
+// are redacted the same way as the logs of the Client.
+//
+// The format of the file depends on its extension: files ending with `.har`
+// are written as an HTTP Archive, which can be opened by the developer tools
+// of most browsers, other files are written as JSON Lines, one request per
+// line. In both cases the new requests are appended to the existing ones.
+type TraceTransport struct {
+	// Base is the RoundTripper actually sending the requests. Defaults to
+	// http.DefaultTransport.
+	Base http.RoundTripper
+
+	file *traceFile
+}
+
+// NewTraceTransport returns a TraceTransport sending the requests with base
+// and recording them to the file at path. Transports recording to the same
+// path share the same file.
+func NewTraceTransport(base http.RoundTripper, path string) *TraceTransport {
+	return &TraceTransport{Base: base, file: openTraceFile(path)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *TraceTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	entry := traceEntry{
+		StartedAt:      time.Now().UTC(),
+		Method:         r.Method,
+		URL:            r.URL.String(),
+		RequestHeaders: RedactHeaders(r.Header),
+	}
+	if r.Body != nil && r.GetBody != nil {
+		if body, err := r.GetBody(); err == nil {
+			bs, _ := ioutil.ReadAll(body)
+			entry.RequestBodySize = len(bs)
+			entry.RequestBody = traceBody(r.URL.Path, r.Header, bs)
+		}
+	}
+
+	resp, err := base.RoundTrip(r)
+	if err != nil {
+		entry.Duration = time.Since(entry.StartedAt)
+		entry.Error = err.Error()
+		t.file.record(entry)
+		return resp, err
+	}
+
+	bs, readErr := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(bs))
+
+	entry.Duration = time.Since(entry.StartedAt)
+	entry.Status = resp.StatusCode
+	entry.StatusText = http.StatusText(resp.StatusCode)
+	entry.ResponseHeaders = RedactHeaders(resp.Header)
+	entry.ResponseBodySize = len(bs)
+	entry.ResponseBody = traceBody(r.URL.Path, resp.Header, bs)
+	if readErr != nil {
+		entry.Error = readErr.Error()
+	}
+	t.file.record(entry)
+
+	return resp, readErr
+}
+
+// traceBody returns the redacted body of a request or response, or an empty
+// string for bodies that aren't JSON, such as uploaded files.
+func traceBody(apiPath string, h http.Header, body []byte) string {
+	if len(body) == 0 || !strings.HasPrefix(h.Get("Content-Type"), "application/json") {
+		return ""
+	}
+	return RedactBody(apiPath, body)
+}
+
+// traceEntry is a single request and its response.
+type traceEntry struct {
+	StartedAt        time.Time     `json:"started_at"`
+	Duration         time.Duration `json:"-"`
+	DurationMS       float64       `json:"duration_ms"`
+	Method           string        `json:"method"`
+	URL              string        `json:"url"`
+	RequestHeaders   http.Header   `json:"request_headers"`
+	RequestBody      string        `json:"request_body,omitempty"`
+	RequestBodySize  int           `json:"request_body_size"`
+	Status           int           `json:"status,omitempty"`
+	StatusText       string        `json:"-"`
+	ResponseHeaders  http.Header   `json:"response_headers,omitempty"`
+	ResponseBody     string        `json:"response_body,omitempty"`
+	ResponseBodySize int           `json:"response_body_size"`
+	Error            string        `json:"error,omitempty"`
+}
+
+var (
+	traceFilesMu sync.Mutex
+	traceFiles   = map[string]*traceFile{}
+)
+
+// traceFile serializes the writes to a trace file.
+type traceFile struct {
+	mu   sync.Mutex
+	path string
+	har  *har
+}
+
+func openTraceFile(path string) *traceFile {
+	traceFilesMu.Lock()
+	defer traceFilesMu.Unlock()
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if f, ok := traceFiles[path]; ok {
+		return f
+	}
+	f := &traceFile{path: path}
+	traceFiles[path] = f
+	return f
+}
+
+// record writes the entry to the trace file. Tracing is a troubleshooting aid,
+// failing to write the file must not fail the request, so errors are only
+// logged.
+func (f *traceFile) record(e traceEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e.DurationMS = float64(e.Duration) / float64(time.Millisecond)
+
+	var err error
+	if strings.EqualFold(filepath.Ext(f.path), ".har") {
+		err = f.writeHAR(e)
+	} else {
+		err = f.writeJSONL(e)
+	}
+	if err != nil {
+		stdLogger{}.Debug("error writing HTTP trace", map[string]interface{}{
+			"path":  f.path,
+			"error": err,
+		})
+	}
+}
+
+func (f *traceFile) writeJSONL(e traceEntry) error {
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(bs, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeHAR rewrites the whole archive, since the entries are nested in a
+// single JSON document. The entries already in the file, for instance the
+// ones written by the plan when tracing an apply, are kept.
+func (f *traceFile) writeHAR(e traceEntry) error {
+	if f.har == nil {
+		f.har = newHAR()
+		bs, err := ioutil.ReadFile(f.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if len(bs) > 0 {
+			if err := json.Unmarshal(bs, f.har); err != nil {
+				return err
+			}
+		}
+	}
+	f.har.Log.Entries = append(f.har.Log.Entries, newHAREntry(e))
+
+	bs, err := json.MarshalIndent(f.har, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so the archive is never left truncated
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, bs, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// The following types implement the subset of the HAR 1.2 format used by the
+// traces, see http://www.softwareishard.com/blog/har-12-spec/.
+
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func newHAR() *har {
+	return &har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "terraform-provider-deploy", Version: "1.0"},
+		Entries: []harEntry{},
+	}}
+}
+
+func newHAREntry(e traceEntry) harEntry {
+	entry := harEntry{
+		StartedDateTime: e.StartedAt.Format(time.RFC3339Nano),
+		Time:            e.DurationMS,
+		Request: harRequest{
+			Method:      e.Method,
+			URL:         e.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(e.RequestHeaders),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    e.RequestBodySize,
+		},
+		Response: harResponse{
+			Status:      e.Status,
+			StatusText:  e.StatusText,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(e.ResponseHeaders),
+			Content: harContent{
+				Size:     e.ResponseBodySize,
+				MimeType: e.ResponseHeaders.Get("Content-Type"),
+				Text:     e.ResponseBody,
+			},
+			HeadersSize: -1,
+			BodySize:    e.ResponseBodySize,
+		},
+		Timings: harTimings{Wait: e.DurationMS},
+		Comment: e.Error,
+	}
+	if u, err := url.Parse(e.URL); err == nil {
+		for k, vs := range u.Query() {
+			for _, v := range vs {
+				entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: k, Value: v})
+			}
+		}
+		sort.Slice(entry.Request.QueryString, func(i, j int) bool {
+			return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+		})
+	}
+	if e.RequestBodySize > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: e.RequestHeaders.Get("Content-Type"),
+			Text:     e.RequestBody,
+		}
+	}
+	return entry
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for k, vs := range h {
+		for _, v := range vs {
+			headers = append(headers, harNameValue{Name: k, Value: v})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTraceTestClient returns a Client sending its requests to a test server
+// through a TraceTransport recording to path.
+func newTraceTestClient(t *testing.T, path string) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "PATCH" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"id":"123","name":"my-project","envVars":["API_KEY"]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	orig := baseURL
+	baseURL, _ = url.Parse(srv.URL)
+	t.Cleanup(func() { baseURL = orig })
+
+	c := New("secret-token")
+	c.Logger = nil
+	c.HTTPClient = &http.Client{Transport: NewTraceTransport(nil, path)}
+	return c
+}
+
+func TestTraceTransport_jsonl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	c := newTraceTestClient(t, path)
+
+	if _, err := c.GetProject("123"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.SetEnvVar("123", "API_KEY", "hunter2"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	entries := []traceEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, secret := range []string{"secret-token", "hunter2"} {
+			if strings.Contains(line, secret) {
+				t.Errorf("expected %q not to be traced, got %s", secret, line)
+			}
+		}
+		e := traceEntry{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Method != "GET" || e.Status != 200 || !strings.Contains(e.ResponseBody, "my-project") {
+		t.Errorf("unexpected first entry: %+v", e)
+	}
+	if e := entries[1]; e.Method != "PATCH" || e.Status != 204 || e.RequestBody != `{"API_KEY":"[REDACTED]"}` {
+		t.Errorf("unexpected second entry: %+v", e)
+	}
+}
+
+func TestTraceTransport_har(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.har")
+
+	// entries already in the archive, e.g. from a previous run, are kept
+	if err := os.WriteFile(path, []byte(`{"log":{"version":"1.2","creator":{"name":"test","version":"1"},"entries":[{"startedDateTime":"2021-01-01T00:00:00Z"}]}}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c := newTraceTestClient(t, path)
+	if _, err := c.GetProject("123"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := c.GetProject("123"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(string(bs), "secret-token") {
+		t.Errorf("expected the token not to be traced")
+	}
+
+	archive := har{}
+	if err := json.Unmarshal(bs, &archive); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(archive.Log.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(archive.Log.Entries))
+	}
+	e := archive.Log.Entries[2]
+	if e.Request.Method != "GET" || e.Response.Status != 200 || e.Response.Content.MimeType != "application/json" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// credentialHelperTimeout is how long the credential helper has to print the
+// token.
+const credentialHelperTimeout = 30 * time.Second
+
+// tokenFileEnvVar is the environment variable the path of the token file is
+// read from when no source is configured on the provider.
+const tokenFileEnvVar = "DEPLOY_TOKEN_FILE"
+
+// tokenEnvVars are the environment variables the token is read from when no
+// other source is configured, by order of precedence.
+var tokenEnvVars = []string{"DEPLOY_TOKEN", "DENO_DEPLOY_TOKEN"}
+
+// providerCredentials are the sources of the API token configured on the
+// provider.
+type providerCredentials struct {
+	APIToken         string
+	TokenFile        string
+	CredentialHelper []string
+}
+
+// token returns the API token along with a description of its source. The
+// sources are, by order of precedence: the api_token argument, the token_file
+// argument, the credential_helper argument, the file at the path of
+// tokenFileEnvVar, and the environment variables of tokenEnvVars. The
+// arguments of the provider always take precedence over the environment. A
+// configured source failing to provide a token is an error, the next sources
+// aren't tried.
+func (c providerCredentials) token(ctx context.Context) (string, string, error) {
+	if c.APIToken != "" {
+		return c.APIToken, "api_token", nil
+	}
+	if c.TokenFile != "" {
+		token, err := readTokenFile("token_file", c.TokenFile)
+		return token, "token_file", err
+	}
+	if len(c.CredentialHelper) > 0 {
+		token, err := runCredentialHelper(ctx, c.CredentialHelper)
+		return token, "credential_helper", err
+	}
+	if path := os.Getenv(tokenFileEnvVar); path != "" {
+		token, err := readTokenFile(tokenFileEnvVar, path)
+		return token, tokenFileEnvVar, err
+	}
+	for _, k := range tokenEnvVars {
+		if v := os.Getenv(k); v != "" {
+			return v, k, nil
+		}
+	}
+	return "", "", errors.New("no API token configured")
+}
+
+// readTokenFile returns the token stored in the file at path, stripped of the
+// surrounding whitespace. A leading `~` is expanded to the home directory. The
+// source is the argument or environment variable the path comes from, used in
+// the errors.
+func readTokenFile(source, path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error expanding %s: %w", source, err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", source, err)
+	}
+	token := strings.TrimSpace(string(bs))
+	if token == "" {
+		return "", fmt.Errorf("%s %s is empty", source, path)
+	}
+	return token, nil
+}
+
+// runCredentialHelper runs the command and returns the token it printed on
+// its standard output, stripped of the surrounding whitespace.
+func runCredentialHelper(ctx context.Context, command []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// the standard output may contain the token, only the standard error
+		// is reported
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("error running credential_helper %s: %w: %s", command[0], err, msg)
+		}
+		return "", fmt.Errorf("error running credential_helper %s: %w", command[0], err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("credential_helper %s printed no token", command[0])
+	}
+	return token, nil
+}
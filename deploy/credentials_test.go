@@ -0,0 +1,144 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProviderCredentials(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		credentials providerCredentials
+		env         map[string]string
+		token       string
+		source      string
+		expectErr   bool
+	}{
+		{
+			name: "api_token first",
+			credentials: providerCredentials{
+				APIToken:         "config-token",
+				TokenFile:        tokenFile,
+				CredentialHelper: []string{"echo", "helper-token"},
+			},
+			env:    map[string]string{"DEPLOY_TOKEN": "env-token"},
+			token:  "config-token",
+			source: "api_token",
+		},
+		{
+			name: "token_file before credential_helper",
+			credentials: providerCredentials{
+				TokenFile:        tokenFile,
+				CredentialHelper: []string{"echo", "helper-token"},
+			},
+			env:    map[string]string{"DEPLOY_TOKEN": "env-token"},
+			token:  "file-token",
+			source: "token_file",
+		},
+		{
+			name:        "credential_helper before environment",
+			credentials: providerCredentials{CredentialHelper: []string{"echo", " helper-token "}},
+			env:         map[string]string{"DEPLOY_TOKEN": "env-token"},
+			token:       "helper-token",
+			source:      "credential_helper",
+		},
+		{
+			name:        "credential_helper before DEPLOY_TOKEN_FILE",
+			credentials: providerCredentials{CredentialHelper: []string{"echo", "helper-token"}},
+			env:         map[string]string{"DEPLOY_TOKEN_FILE": tokenFile},
+			token:       "helper-token",
+			source:      "credential_helper",
+		},
+		{
+			name:        "token_file before DEPLOY_TOKEN_FILE",
+			credentials: providerCredentials{TokenFile: tokenFile},
+			env:         map[string]string{"DEPLOY_TOKEN_FILE": emptyFile},
+			token:       "file-token",
+			source:      "token_file",
+		},
+		{
+			name:   "DEPLOY_TOKEN_FILE before DEPLOY_TOKEN",
+			env:    map[string]string{"DEPLOY_TOKEN_FILE": tokenFile, "DEPLOY_TOKEN": "env-token"},
+			token:  "file-token",
+			source: "DEPLOY_TOKEN_FILE",
+		},
+		{
+			name:   "DEPLOY_TOKEN before DENO_DEPLOY_TOKEN",
+			env:    map[string]string{"DEPLOY_TOKEN": "env-token", "DENO_DEPLOY_TOKEN": "deno-env-token"},
+			token:  "env-token",
+			source: "DEPLOY_TOKEN",
+		},
+		{
+			name:   "DENO_DEPLOY_TOKEN",
+			env:    map[string]string{"DENO_DEPLOY_TOKEN": "deno-env-token"},
+			token:  "deno-env-token",
+			source: "DENO_DEPLOY_TOKEN",
+		},
+		{
+			name:      "none",
+			expectErr: true,
+		},
+		{
+			name:        "missing token_file",
+			credentials: providerCredentials{TokenFile: filepath.Join(dir, "missing")},
+			env:         map[string]string{"DEPLOY_TOKEN": "env-token"},
+			expectErr:   true,
+		},
+		{
+			name:        "empty token_file",
+			credentials: providerCredentials{TokenFile: emptyFile},
+			expectErr:   true,
+		},
+		{
+			name:      "missing DEPLOY_TOKEN_FILE",
+			env:       map[string]string{"DEPLOY_TOKEN_FILE": filepath.Join(dir, "missing"), "DEPLOY_TOKEN": "env-token"},
+			expectErr: true,
+		},
+		{
+			name:        "failing credential_helper",
+			credentials: providerCredentials{CredentialHelper: []string{"false"}},
+			env:         map[string]string{"DEPLOY_TOKEN": "env-token"},
+			expectErr:   true,
+		},
+		{
+			name:        "silent credential_helper",
+			credentials: providerCredentials{CredentialHelper: []string{"true"}},
+			expectErr:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, k := range append([]string{tokenFileEnvVar}, tokenEnvVars...) {
+				t.Setenv(k, c.env[k])
+			}
+
+			token, source, err := c.credentials.token(context.Background())
+			if c.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got token from %s", source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if token != c.token || source != c.source {
+				t.Errorf("expected %q from %s, got %q from %s", c.token, c.source, token, source)
+			}
+		})
+	}
+}
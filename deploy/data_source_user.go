@@ -2,16 +2,18 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
-	"log"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/wperron/terraform-deploy-provider/client"
 )
 
 func dataSourceUser() *schema.Resource {
 	return &schema.Resource{
-		Read: dataSourceUserRead,
+		ReadContext: dataSourceUserRead,
 		Schema: map[string]*schema.Schema{
 			"id": {
 				Type:     schema.TypeString,
@@ -30,26 +32,29 @@ func dataSourceUser() *schema.Resource {
 	}
 }
 
-func dataSourceUserRead(d *schema.ResourceData, m interface{}) error {
+func dataSourceUserRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	c := m.(*client.Client)
 
 	res, err := c.CurrentUser()
 
 	if err != nil {
-		return fmt.Errorf("error getting Current User: %w", err)
+		return diag.Errorf("error getting Current User: %s", err)
 	}
 
-	log.Printf("[DEBUG] Received Caller Identity: %s %s", res.ID, res.Name)
+	tflog.Debug(withLogMasking(ctx), "received caller identity", map[string]interface{}{
+		"id":   res.ID,
+		"name": res.Name,
+	})
 
 	d.SetId(res.ID)
 	if err := d.Set("id", res.ID); err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 	if err := d.Set("name", res.Name); err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 	if err := d.Set("github_id", fmt.Sprint(res.GitHubID)); err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	return nil
@@ -0,0 +1,121 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/net/idna"
+)
+
+// domainNameProfile is the IDNA profile used to convert user-provided domain
+// names to the form expected by the Deploy API: lowercase, punycode-encoded
+// ASCII labels that are valid host names.
+var domainNameProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(true),
+	idna.Transitional(false),
+	idna.ValidateLabels(true),
+	idna.VerifyDNSLength(true),
+)
+
+// wildcardPrefix is the leftmost label of a wildcard domain name.
+const wildcardPrefix = "*."
+
+// isWildcardDomainName reports whether the domain name is a wildcard, like
+// `*.apps.example.com`.
+func isWildcardDomainName(name string) bool {
+	return strings.HasPrefix(name, wildcardPrefix)
+}
+
+// wildcardBaseDomainName returns the domain name covered by a wildcard, that is
+// the name without its leftmost `*` label. Non-wildcard names are returned as
+// is.
+func wildcardBaseDomainName(name string) string {
+	return strings.TrimPrefix(name, wildcardPrefix)
+}
+
+// normalizeDomainName returns the canonical form of a domain name, that is its
+// lowercase punycode representation without the trailing dot of a fully
+// qualified name. `Example.org`, `example.org.` and `EXAMPLE.ORG` all
+// normalize to `example.org`, and `bücher.example` normalizes to
+// `xn--bcher-kva.example`.
+//
+// Wildcard domain names are supported as long as the wildcard is the whole
+// leftmost label, for instance `*.apps.example.com`.
+func normalizeDomainName(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return "", fmt.Errorf("domain name cannot be empty")
+	}
+
+	prefix := ""
+	if isWildcardDomainName(name) {
+		prefix = wildcardPrefix
+		name = wildcardBaseDomainName(name)
+	}
+
+	ascii, err := domainNameProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain name %q: %w", prefix+name, err)
+	}
+
+	if !strings.Contains(ascii, ".") {
+		return "", fmt.Errorf("invalid domain name %q: must contain at least two labels", prefix+name)
+	}
+
+	return prefix + ascii, nil
+}
+
+// validateDomainName is a SchemaValidateFunc checking that the value is a
+// valid, normalizable domain name.
+func validateDomainName(v interface{}, k string) ([]string, []error) {
+	value, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %q to be string", k)}
+	}
+
+	if _, err := normalizeDomainName(value); err != nil {
+		return nil, []error{fmt.Errorf("%q: %w", k, err)}
+	}
+
+	return nil, nil
+}
+
+// errWildcardCertificate is returned when validating a wildcard domain name:
+// their certificates need a DNS-01 challenge, which Deploy can't provision
+// automatically.
+var errWildcardCertificate = errors.New("certificates can't be provisioned automatically for wildcard domain names")
+
+// validateNonWildcardDomainName is a SchemaValidateFunc checking that the value
+// is a valid domain name that is not a wildcard.
+func validateNonWildcardDomainName(v interface{}, k string) ([]string, []error) {
+	if warnings, errs := validateDomainName(v, k); len(errs) > 0 {
+		return warnings, errs
+	}
+
+	if isWildcardDomainName(v.(string)) {
+		return nil, []error{fmt.Errorf("%q: %w", k, errWildcardCertificate)}
+	}
+
+	return nil, nil
+}
+
+// suppressEquivalentDomainName suppresses the diff between two domain names
+// that normalize to the same value, for instance an IDN and its punycode form.
+func suppressEquivalentDomainName(k, old, new string, d *schema.ResourceData) bool {
+	o, err := normalizeDomainName(old)
+	if err != nil {
+		return false
+	}
+
+	n, err := normalizeDomainName(new)
+	if err != nil {
+		return false
+	}
+
+	return o == n
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeDomainName(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"example.org", "example.org", true},
+		{"Example.ORG", "example.org", true},
+		{"example.org.", "example.org", true},
+		{"foo.bar.example.org", "foo.bar.example.org", true},
+		{"bücher.example", "xn--bcher-kva.example", true},
+		{"BÜCHER.example.", "xn--bcher-kva.example", true},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", true},
+		{"*.apps.example.com", "*.apps.example.com", true},
+		{"*.Apps.Example.com.", "*.apps.example.com", true},
+		{"*.bücher.example", "*.xn--bcher-kva.example", true},
+		{"", "", false},
+		{".", "", false},
+		{"localhost", "", false},
+		{"foo..example.org", "", false},
+		{"foo_bar.example.org", "", false},
+		{"-foo.example.org", "", false},
+		{"foo bar.example.org", "", false},
+		{"*.org", "", false},
+		{"*", "", false},
+		{"foo.*.example.org", "", false},
+		{"*foo.example.org", "", false},
+		{"*.*.example.org", "", false},
+	}
+
+	for _, c := range cases {
+		actual, err := normalizeDomainName(c.input)
+		if c.valid && err != nil {
+			t.Errorf("normalizeDomainName(%q): unexpected error: %s", c.input, err)
+			continue
+		}
+		if !c.valid && err == nil {
+			t.Errorf("normalizeDomainName(%q): expected an error, got %q", c.input, actual)
+			continue
+		}
+		if actual != c.expected {
+			t.Errorf("normalizeDomainName(%q): expected %q, got %q", c.input, c.expected, actual)
+		}
+	}
+}
+
+func TestSuppressEquivalentDomainName(t *testing.T) {
+	cases := []struct {
+		old      string
+		new      string
+		expected bool
+	}{
+		{"example.org", "example.org", true},
+		{"example.org", "Example.org.", true},
+		{"xn--bcher-kva.example", "bücher.example", true},
+		{"*.apps.example.com", "*.APPS.example.com.", true},
+		{"*.apps.example.com", "apps.example.com", false},
+		{"example.org", "example.com", false},
+		{"example.org", "", false},
+	}
+
+	for _, c := range cases {
+		if actual := suppressEquivalentDomainName("domain_name", c.old, c.new, nil); actual != c.expected {
+			t.Errorf("suppressEquivalentDomainName(%q, %q): expected %t, got %t", c.old, c.new, c.expected, actual)
+		}
+	}
+}
+
+func TestValidateNonWildcardDomainName(t *testing.T) {
+	if _, errs := validateNonWildcardDomainName("apps.example.com", "custom_domain"); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if _, errs := validateNonWildcardDomainName("foo..example.org", "custom_domain"); len(errs) == 0 {
+		t.Errorf("expected an error for an invalid domain name")
+	}
+
+	_, errs := validateNonWildcardDomainName("*.apps.example.com", "custom_domain")
+	if len(errs) != 1 || !errors.Is(errs[0], errWildcardCertificate) {
+		t.Errorf("expected a wildcard certificate error, got %v", errs)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseDotenv parses the content of a dotenv file into a map of environment
+// variables.
+//
+// Each variable is declared as `KEY=value`, optionally prefixed by `export`.
+// Blank lines and lines starting with `#` are ignored. Values are either:
+//
+//   - unquoted, in which case surrounding whitespace and comments starting with
+//     ` #` are stripped;
+//   - single quoted, in which case the value is taken literally;
+//   - double quoted, in which case `\n`, `\r`, `\t`, `\"`, `\\` and `\$` are
+//     escape sequences.
+//
+// Quoted values can span multiple lines. Variables are not expanded. When a key
+// is declared more than once, the last value wins.
+func parseDotenv(content string) (map[string]string, error) {
+	p := &dotenvParser{src: strings.ReplaceAll(content, "\r\n", "\n"), line: 1}
+	vars := map[string]string{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return vars, nil
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+}
+
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *dotenvParser) errorf(line int, format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, a...))
+}
+
+// skipBlank skips whitespace, new lines and comments.
+func (p *dotenvParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipToEndOfLine()
+		default:
+			return
+		}
+	}
+}
+
+func (p *dotenvParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *dotenvParser) skipToEndOfLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+func (p *dotenvParser) parseKey() (string, error) {
+	if rest := p.src[p.pos:]; strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+		p.pos += len("export")
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for !p.eof() && isDotenvKeyChar(p.peek(), p.pos == start) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return "", p.errorf(p.line, "expected a key starting with a letter or an underscore")
+	}
+
+	p.skipSpaces()
+	if p.eof() || p.peek() != '=' {
+		return "", p.errorf(p.line, "expected '=' after key %q", key)
+	}
+	p.pos++
+	p.skipSpaces()
+	return key, nil
+}
+
+func isDotenvKeyChar(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
+
+func (p *dotenvParser) parseValue() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+
+	switch p.peek() {
+	case '\'':
+		return p.parseSingleQuoted()
+	case '"':
+		return p.parseDoubleQuoted()
+	}
+
+	start := p.pos
+	p.skipToEndOfLine()
+	value := p.src[start:p.pos]
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, "\t#"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func (p *dotenvParser) parseSingleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end < 0 {
+		return "", p.errorf(line, "unterminated single quoted value")
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+	return value, p.endOfQuotedValue()
+}
+
+func (p *dotenvParser) parseDoubleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf(line, "unterminated double quoted value")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), p.endOfQuotedValue()
+		case '\n':
+			p.line++
+			b.WriteByte(c)
+		case '\\':
+			if p.eof() {
+				return "", p.errorf(line, "unterminated double quoted value")
+			}
+			e := p.peek()
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(e)
+			default:
+				return "", p.errorf(p.line, "invalid escape sequence \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// endOfQuotedValue checks that nothing but a comment follows a quoted value on
+// its last line.
+func (p *dotenvParser) endOfQuotedValue() error {
+	p.skipSpaces()
+	if p.eof() || p.peek() == '\n' {
+		return nil
+	}
+	if p.peek() == '#' {
+		p.skipToEndOfLine()
+		return nil
+	}
+	return p.errorf(p.line, "unexpected character %q after quoted value", p.peek())
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		expected map[string]string
+	}{
+		{
+			name:     "empty",
+			content:  "",
+			expected: map[string]string{},
+		},
+		{
+			name:     "unquoted",
+			content:  "FOO=bar\nFRUIT = banana \n",
+			expected: map[string]string{"FOO": "bar", "FRUIT": "banana"},
+		},
+		{
+			name:     "comments and blank lines",
+			content:  "# comment\n\n  FOO=bar # trailing comment\n\t# indented comment\nURL=https://example.com/#anchor\n",
+			expected: map[string]string{"FOO": "bar", "URL": "https://example.com/#anchor"},
+		},
+		{
+			name:     "export",
+			content:  "export FOO=bar\nexporter=baz\n",
+			expected: map[string]string{"FOO": "bar", "exporter": "baz"},
+		},
+		{
+			name:     "empty value",
+			content:  "FOO=\nBAR=''\nBAZ=\"\"",
+			expected: map[string]string{"FOO": "", "BAR": "", "BAZ": ""},
+		},
+		{
+			name:     "single quoted",
+			content:  `FOO='bar \n # baz' # comment`,
+			expected: map[string]string{"FOO": `bar \n # baz`},
+		},
+		{
+			name:     "double quoted",
+			content:  `FOO="a\"b\\c\$d\te\nf" # comment`,
+			expected: map[string]string{"FOO": "a\"b\\c$d\te\nf"},
+		},
+		{
+			name:     "multiline",
+			content:  "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nSQL='SELECT *\nFROM t'\nNEXT=1\n",
+			expected: map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "SQL": "SELECT *\nFROM t", "NEXT": "1"},
+		},
+		{
+			name:     "crlf",
+			content:  "FOO=bar\r\nBAZ=\"a\r\nb\"\r\n",
+			expected: map[string]string{"FOO": "bar", "BAZ": "a\nb"},
+		},
+		{
+			name:     "last value wins",
+			content:  "FOO=bar\nFOO=baz\n",
+			expected: map[string]string{"FOO": "baz"},
+		},
+	}
+
+	for _, c := range cases {
+		actual, err := parseDotenv(c.content)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestParseDotenv_errors(t *testing.T) {
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"FOO", `line 1: expected '=' after key "FOO"`},
+		{"FOO=bar\n\nFOO BAR=baz", `line 3: expected '=' after key "FOO"`},
+		{"FOO=bar\n1FOO=baz", "line 2: expected a key starting with a letter or an underscore"},
+		{"FOO=bar\nBAR='baz\n\n", "line 2: unterminated single quoted value"},
+		{"FOO=\"bar\nbaz", "line 1: unterminated double quoted value"},
+		{"FOO=\"a\nb\\x\"", `line 2: invalid escape sequence \x`},
+		{"FOO=\"a\nb\" c", `line 2: unexpected character 'c' after quoted value`},
+	}
+
+	for _, c := range cases {
+		_, err := parseDotenv(c.content)
+		if err == nil {
+			t.Errorf("parseDotenv(%q): expected an error", c.content)
+			continue
+		}
+		if err.Error() != c.expected {
+			t.Errorf("parseDotenv(%q): expected error %q, got %q", c.content, c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+// clientLogSubsystem is the tflog subsystem of the logs of the Deploy client.
+// Its level can be set independently with TF_LOG_PROVIDER_DEPLOY_CLIENT.
+const clientLogSubsystem = "client"
+
+// maskedLogFieldKeys are the keys of the log fields whose values are masked,
+// in addition to the redaction already done by the client.
+var maskedLogFieldKeys = []string{"api_token", "token", "Authorization", "value", "secret_value"}
+
+// withLogMasking returns a context whose provider logs have the sensitive
+// values masked.
+func withLogMasking(ctx context.Context) context.Context {
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, maskedLogFieldKeys...)
+	ctx = tflog.MaskAllFieldValuesRegexes(ctx, client.PrivateKeyRegexp)
+	return tflog.MaskMessageRegexes(ctx, client.PrivateKeyRegexp)
+}
+
+// tflogLogger is a client.Logger forwarding the logs of the client to the
+// `client` tflog subsystem.
+//
+// The client isn't context aware, so the logger keeps the context of the
+// provider's configuration. Its logs are therefore annotated with the RPC
+// that configured the provider rather than the one in progress.
+type tflogLogger struct {
+	ctx context.Context
+}
+
+// newClientLogger returns a client.Logger writing to the `client` tflog
+// subsystem of the given context, masking the sensitive values.
+func newClientLogger(ctx context.Context) client.Logger {
+	ctx = tflog.NewSubsystem(ctx, clientLogSubsystem)
+	ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, clientLogSubsystem, maskedLogFieldKeys...)
+	ctx = tflog.SubsystemMaskAllFieldValuesRegexes(ctx, clientLogSubsystem, client.PrivateKeyRegexp)
+	return tflogLogger{ctx: ctx}
+}
+
+func (l tflogLogger) Debug(msg string, fields map[string]interface{}) {
+	tflog.SubsystemDebug(l.ctx, clientLogSubsystem, msg, fields)
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"sync"
+)
+
+// projectMutexKV serializes the operations modifying the same project. The
+// resources of a configuration are applied in parallel, and several of them
+// can modify the same project, e.g. a `deploy_project` updating its
+// environment variables while a `deploy_env_var` sets another one. Without
+// locking, the read-modify-write cycles of the API calls can interleave and
+// overwrite each other's changes. Different projects are still modified in
+// parallel.
+var projectMutexKV = newMutexKV()
+
+// mutexKV is a map of mutexes, locked by key.
+type mutexKV struct {
+	lock  sync.Mutex
+	store map[string]*sync.Mutex
+}
+
+func newMutexKV() *mutexKV {
+	return &mutexKV{
+		store: make(map[string]*sync.Mutex),
+	}
+}
+
+// Lock locks the mutex of the given key, creating it if needed.
+func (m *mutexKV) Lock(key string) {
+	m.get(key).Lock()
+}
+
+// Unlock unlocks the mutex of the given key.
+func (m *mutexKV) Unlock(key string) {
+	m.get(key).Unlock()
+}
+
+// get returns the mutex of the given key, creating it if needed. Mutexes are
+// never removed, a provider run only touches a bounded number of projects.
+func (m *mutexKV) get(key string) *sync.Mutex {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	mutex, ok := m.store[key]
+	if !ok {
+		mutex = &sync.Mutex{}
+		m.store[key] = mutex
+	}
+	return mutex
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMutexKV(t *testing.T) {
+	m := newMutexKV()
+	m.Lock("project-a")
+
+	// a different key isn't blocked
+	done := make(chan struct{})
+	go func() {
+		m.Lock("project-b")
+		m.Unlock("project-b")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a different key not to be blocked")
+	}
+
+	// the same key is blocked until unlocked
+	locked := make(chan struct{})
+	go func() {
+		m.Lock("project-a")
+		close(locked)
+		m.Unlock("project-a")
+	}()
+	select {
+	case <-locked:
+		t.Fatal("expected the same key to be blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.Unlock("project-a")
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the key to be unlocked")
+	}
+}
@@ -3,9 +3,15 @@ package deploy
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/wperron/terraform-deploy-provider/client"
 )
 
@@ -15,15 +21,48 @@ func Provider() *schema.Provider {
 		Schema: map[string]*schema.Schema{
 			"api_token": {
 				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("DEPLOY_TOKEN", nil),
+				Optional:    true,
+				Sensitive:   true,
 				Description: "API Token used for accessing Deno Deploy",
 			},
+			"token_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path to a file containing the API Token",
+			},
+			"credential_helper": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MinItems:    1,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Command and arguments of a program printing the API Token",
+			},
+			"max_requests_per_second": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      10,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of requests per second sent to Deno Deploy, 0 to disable the limit",
+			},
+			"skip_credentials_validation": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip the validation of the API token when configuring the provider",
+			},
+			"cache_domains": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to read the custom domains of each project with a single request per run",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"deploy_project":                  resourceProject(),
 			"deploy_custom_domain":            resourceCustomDomain(),
 			"deploy_custom_domain_validation": resourceCustomDomainValidation(),
+			"deploy_github_link":              resourceGitHubLink(),
+			"deploy_env_var":                  resourceEnvVar(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
 			"deploy_user": dataSourceUser(),
@@ -33,12 +72,94 @@ func Provider() *schema.Provider {
 }
 
 func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	token := d.Get("api_token").(string)
+	c, diags := newProviderClient(ctx, d)
+	if diags.HasError() {
+		return nil, diags
+	}
+	return validateProviderClient(ctx, d, c)
+}
 
-	// Warning or errors can be collected in a slice type
-	var diags diag.Diagnostics
+// newProviderClient returns the client shared by all the resources.
+func newProviderClient(ctx context.Context, d *schema.ResourceData) (*client.Client, diag.Diagnostics) {
+	credentials := providerCredentials{
+		APIToken:  d.Get("api_token").(string),
+		TokenFile: d.Get("token_file").(string),
+	}
+	for _, arg := range d.Get("credential_helper").([]interface{}) {
+		credentials.CredentialHelper = append(credentials.CredentialHelper, arg.(string))
+	}
+	token, source, err := credentials.token(ctx)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Missing Deno Deploy credentials",
+			Detail: fmt.Sprintf("Unable to get the API token: %s\n\nSet one of `api_token`, `token_file` or "+
+				"`credential_helper`, or the DEPLOY_TOKEN or DENO_DEPLOY_TOKEN environment variable.", err),
+		}}
+	}
+	tflog.Debug(withLogMasking(ctx), "using API token", map[string]interface{}{
+		"source": source,
+	})
 
 	c := client.New(token)
+	c.Logger = newClientLogger(withLogMasking(ctx))
+	// the client is shared by all the resources, so is its rate limiter. A
+	// limit of 0 only disables the limiter's own rate, the rate limit headers
+	// of the API are still followed.
+	rps := d.Get("max_requests_per_second").(int)
+	c.RateLimiter = client.NewRateLimiter(float64(rps), rps)
+	c.CacheDomains = d.Get("cache_domains").(bool)
+	if path := os.Getenv("DEPLOY_HTTP_TRACE"); path != "" {
+		c.HTTPClient = &http.Client{Transport: client.NewTraceTransport(http.DefaultTransport, path)}
+	}
+	return c, nil
+}
+
+// validateProviderClient checks that the token of the client is valid, unless
+// skip_credentials_validation is set, so that invalid tokens are reported once
+// when configuring the provider rather than as errors of the first resource.
+func validateProviderClient(ctx context.Context, d *schema.ResourceData, c *client.Client) (interface{}, diag.Diagnostics) {
+	if d.Get("skip_credentials_validation").(bool) {
+		return c, nil
+	}
+
+	user, err := c.CurrentUser()
+	if err != nil {
+		return nil, credentialsDiagnostics(err)
+	}
+	if user.IsBlocked {
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Blocked Deno Deploy account",
+			Detail:   fmt.Sprintf("The account %q owning the API token is blocked by Deno Deploy.", user.Login),
+		}}
+	}
+
+	tflog.Debug(withLogMasking(ctx), "validated credentials", map[string]interface{}{
+		"user": user.Login,
+	})
+	return c, nil
+}
+
+// credentialsDiagnostics returns the diagnostics of a failed validation of the
+// credentials.
+func credentialsDiagnostics(err error) diag.Diagnostics {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Invalid Deno Deploy credentials",
+			Detail: fmt.Sprintf("Deno Deploy rejected the API token (status %d). Check that the configured "+
+				"credentials are a valid access token, which can be created at "+
+				"https://dash.deno.com/account#access-tokens. Tokens are revoked when deleted from the dashboard.",
+				apiErr.StatusCode),
+		}}
+	}
 
-	return c, diags
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "Unable to validate Deno Deploy credentials",
+		Detail: fmt.Sprintf("Requesting the current user failed: %s\n\nSet `skip_credentials_validation` "+
+			"to `true` to configure the provider without contacting Deno Deploy, e.g. to plan offline.", err),
+	}}
 }
@@ -2,11 +2,17 @@
 package deploy
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/wperron/terraform-deploy-provider/client"
 )
 
 var testAccProviders map[string]*schema.Provider
@@ -19,11 +25,28 @@ func init() {
 		"deploy": testAccProvider,
 	}
 
+	// send the requests of the provider through the recorder of the running
+	// test, if any
+	testAccProvider.ConfigureContextFunc = func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		c, diags := newProviderClient(ctx, d)
+		if diags.HasError() {
+			return nil, diags
+		}
+		if testAccRecorder != nil {
+			c.HTTPClient = &http.Client{Transport: testAccRecorder}
+		}
+		return validateProviderClient(ctx, d, c)
+	}
+
+	token := testToken
+	if testMode() == testModeReplay {
+		token = testReplayToken
+	}
 	providerConfig = fmt.Sprintf(`
   provider "deploy" {
     api_token = "%s"
   }
-`, testToken)
+`, token)
 }
 
 func TestProvider(t *testing.T) {
@@ -38,7 +61,7 @@ func TestProvider_impl(t *testing.T) {
 
 func TestAccProviderConfigure(t *testing.T) {
 	resource.Test(t, resource.TestCase{
-		PreCheck:  func() {},
+		PreCheck:  func() { testAccPreCheck(t) },
 		Providers: testAccProviders,
 		Steps: []resource.TestStep{
 			{
@@ -48,3 +71,40 @@ func TestAccProviderConfigure(t *testing.T) {
 		},
 	})
 }
+
+func TestProviderConfigure_skipCredentialsValidation(t *testing.T) {
+	p := Provider()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"api_token":                   "invalid",
+		"skip_credentials_validation": true,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, ok := p.Meta().(*client.Client); !ok {
+		t.Errorf("expected the provider to be configured with a client")
+	}
+}
+
+func TestCredentialsDiagnostics(t *testing.T) {
+	cases := []struct {
+		err     error
+		summary string
+	}{
+		{&client.APIError{StatusCode: 401}, "Invalid Deno Deploy credentials"},
+		{&client.APIError{StatusCode: 403}, "Invalid Deno Deploy credentials"},
+		{fmt.Errorf("wrapped: %w", &client.APIError{StatusCode: 401}), "Invalid Deno Deploy credentials"},
+		{&client.APIError{StatusCode: 500}, "Unable to validate Deno Deploy credentials"},
+		{errors.New("dial tcp: lookup dash.deno.com: no such host"), "Unable to validate Deno Deploy credentials"},
+	}
+
+	for _, c := range cases {
+		diags := credentialsDiagnostics(c.err)
+		if len(diags) != 1 || diags[0].Severity != diag.Error {
+			t.Fatalf("expected a single error for %q, got %v", c.err, diags)
+		}
+		if diags[0].Summary != c.summary {
+			t.Errorf("expected summary %q for %q, got %q", c.summary, c.err, diags[0].Summary)
+		}
+	}
+}
@@ -2,14 +2,118 @@
 package deploy
 
 import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/wperron/terraform-deploy-provider/client"
 )
 
 var testToken string = os.Getenv("DEPLOY_TOKEN")
 
+// The acceptance tests run in one of the following modes, selected with the
+// DEPLOY_TEST_MODE environment variable.
+const (
+	// testModeLive runs the tests against Deploy without recording anything.
+	// It is the default until every test has a cassette.
+	testModeLive = "live"
+	// testModeRecord runs the tests against Deploy and records their cassettes.
+	testModeRecord = "record"
+	// testModeReplay replays the cassettes of testdata/fixtures, without a
+	// token or network access. Tests without a cassette fail.
+	testModeReplay = "replay"
+)
+
+// testReplayToken is the token used to replay the cassettes, which never
+// contain the token they were recorded with.
+const testReplayToken = "replay-token"
+
+// testAccRecorder is the recorder of the running acceptance test, installed as
+// the transport of the provider's client. The acceptance tests must not run in
+// parallel.
+var testAccRecorder *client.Recorder
+
+func testMode() string {
+	if v := os.Getenv("DEPLOY_TEST_MODE"); v != "" {
+		return v
+	}
+	return testModeLive
+}
+
 func testAccPreCheck(t *testing.T) {
-	if v := os.Getenv("DEPLOY_TOKEN"); v == "" {
-		t.Fatal("DEPLOY_TOKEN must be set for acceptance tests")
+	mode := testMode()
+	switch mode {
+	case testModeReplay:
+		recorder, err := client.NewRecorder(testAccCassettePath(t), client.RecorderModeReplay)
+		// a missing cassette fails the test rather than skipping it, so that
+		// the tests can't silently stop running
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("no cassette recorded for %s, run with DEPLOY_TEST_MODE=record to record one", t.Name())
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("DEPLOY_TOKEN", testReplayToken)
+		testAccUseRecorder(t, recorder)
+	case testModeRecord:
+		if v := os.Getenv("DEPLOY_TOKEN"); v == "" {
+			t.Fatal("DEPLOY_TOKEN must be set to record acceptance tests")
+		}
+		recorder, err := client.NewRecorder(testAccCassettePath(t), client.RecorderModeRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAccUseRecorder(t, recorder)
+	case testModeLive:
+		if v := os.Getenv("DEPLOY_TOKEN"); v == "" {
+			t.Fatal("DEPLOY_TOKEN must be set for acceptance tests")
+		}
+	default:
+		t.Fatalf("invalid DEPLOY_TEST_MODE %q, expected one of %s, %s or %s", mode, testModeReplay, testModeRecord, testModeLive)
+	}
+}
+
+// testAccUseRecorder installs the recorder until the end of the test, and
+// saves its cassette if the test succeeded.
+func testAccUseRecorder(t *testing.T, recorder *client.Recorder) {
+	testAccRecorder = recorder
+	// the health checks are recorded as well, the deployments they probe
+	// don't exist anymore when replaying
+	healthCheckClient := healthCheckHTTPClient
+	healthCheckHTTPClient = &http.Client{Timeout: healthCheckProbeTimeout, Transport: recorder}
+	t.Cleanup(func() {
+		testAccRecorder = nil
+		healthCheckHTTPClient = healthCheckClient
+		if t.Failed() {
+			return
+		}
+		if err := recorder.Save(); err != nil {
+			t.Errorf("error saving cassette: %s", err)
+		}
+	})
+}
+
+func testAccCassettePath(t *testing.T) string {
+	return filepath.Join("testdata", "fixtures", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+}
+
+// testAccRandomID returns a random suffix for the names of the resources of a
+// test. When recording or replaying, the suffix is derived from the name of
+// the test instead, so the requests of the replay match the recorded ones.
+func testAccRandomID(t *testing.T) string {
+	if testMode() == testModeLive {
+		return acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	}
+
+	sum := sha256.Sum256([]byte(t.Name()))
+	id := make([]byte, 4)
+	for i := range id {
+		id[i] = acctest.CharSetAlphaNum[int(sum[i])%len(acctest.CharSetAlphaNum)]
 	}
+	return string(id)
 }
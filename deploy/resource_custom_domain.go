@@ -8,6 +8,12 @@ import (
 	"github.com/wperron/terraform-deploy-provider/client"
 )
 
+// Addresses custom domain names must point to.
+const (
+	customDomainIPv4 = "34.120.54.55"
+	customDomainIPv6 = "2600:1901:0:6d85::"
+)
+
 func resourceCustomDomain() *schema.Resource {
 	return &schema.Resource{
 		Create: createCustomDomain,
@@ -21,9 +27,11 @@ func resourceCustomDomain() *schema.Resource {
 				ForceNew: true,
 			},
 			"domain_name": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateFunc:     validateDomainName,
+				DiffSuppressFunc: suppressEquivalentDomainName,
 			},
 			"records": {
 				Type:     schema.TypeList,
@@ -52,6 +60,10 @@ func resourceCustomDomain() *schema.Resource {
 				Type:     schema.TypeBool,
 				Computed: true,
 			},
+			"is_wildcard": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
 		},
 	}
 }
@@ -59,7 +71,12 @@ func resourceCustomDomain() *schema.Resource {
 func createCustomDomain(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
 	project := d.Get("project_id").(string)
-	domain := d.Get("domain_name").(string)
+	domain, err := normalizeDomainName(d.Get("domain_name").(string))
+	if err != nil {
+		return err
+	}
+	projectMutexKV.Lock(project)
+	defer projectMutexKV.Unlock(project)
 
 	if _, err := c.AddDomain(project, client.Domain{
 		Domain: domain,
@@ -73,38 +90,59 @@ func createCustomDomain(d *schema.ResourceData, meta interface{}) error {
 
 func readCustomDomain(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
-	domain, err := c.GetDomain(d.Get("project_id").(string), d.Get("domain_name").(string))
+	domain, err := c.GetDomain(d.Get("project_id").(string), d.Id())
 	if err != nil {
 		return err
 	}
 
-	if err := d.Set("records", []map[string]interface{}{
+	if err := d.Set("records", customDomainRecords(domain)); err != nil {
+		return err
+	}
+
+	if err := d.Set("is_validated", domain.IsValidated); err != nil {
+		return err
+	}
+	if err := d.Set("is_wildcard", isWildcardDomainName(domain.Domain)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func deleteCustomDomain(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID := d.Get("project_id").(string)
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
+	return c.DeleteDomain(projectID, d.Id())
+}
+
+// customDomainRecords returns the DNS records that must be created for a
+// custom domain name to be validated and served by Deploy.
+//
+// Wildcard domains are validated on the domain they cover, since a TXT record
+// cannot be looked up on a wildcard name.
+func customDomainRecords(domain client.Domain) []map[string]interface{} {
+	validationName := domain.Domain
+	if isWildcardDomainName(domain.Domain) {
+		validationName = wildcardBaseDomainName(domain.Domain)
+	}
+
+	return []map[string]interface{}{
 		{
 			"domain_name": domain.Domain,
 			"type":        "A",
-			"value":       "34.120.54.55",
+			"value":       customDomainIPv4,
 		},
 		{
 			"domain_name": domain.Domain,
 			"type":        "AAAA",
-			"value":       "2600:1901:0:6d85::",
+			"value":       customDomainIPv6,
 		},
 		{
-			"domain_name": domain.Domain,
+			"domain_name": validationName,
 			"type":        "TXT",
 			"value":       fmt.Sprintf("deno-com-validation=%s", domain.Token),
 		},
-	}); err != nil {
-		return err
-	}
-
-	if err := d.Set("is_validated", domain.IsValidated); err != nil {
-		return err
 	}
-	return nil
-}
-
-func deleteCustomDomain(d *schema.ResourceData, meta interface{}) error {
-	c := meta.(*client.Client)
-	return c.DeleteDomain(d.Get("project_id").(string), d.Id())
 }
@@ -5,14 +5,13 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/wperron/terraform-deploy-provider/client"
 )
 
 func TestAccCustomDomain_basic(t *testing.T) {
-	randomID := acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	randomID := testAccRandomID(t)
 
 	config := fmt.Sprintf(testAccCustomDomainConfig_basic, randomID, randomID)
 	randomDomain := fmt.Sprintf("foo-%s.example.org", randomID)
@@ -51,6 +50,73 @@ func TestAccCustomDomain_basic(t *testing.T) {
 	})
 }
 
+func TestAccCustomDomain_normalized(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccCustomDomainConfig_normalized, randomID, randomID)
+	randomDomain := fmt.Sprintf("foo-%s.example.org", randomID)
+
+	var project client.Project
+	var domain client.Domain
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCustomDomainCheckDestroy(&project, &domain),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCustomDomainCheckExists("deploy_custom_domain.test", &project, &domain),
+					resource.TestCheckResourceAttr(
+						"deploy_custom_domain.test", "id", randomDomain,
+					),
+				),
+			},
+			{
+				// The configured name differs from the normalized ID only by case
+				// and the trailing dot, so no changes should be planned.
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccCustomDomain_wildcard(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccCustomDomainConfig_wildcard, randomID, randomID)
+	randomDomain := fmt.Sprintf("*.apps-%s.example.org", randomID)
+
+	var project client.Project
+	var domain client.Domain
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCustomDomainCheckDestroy(&project, &domain),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCustomDomainCheckExists("deploy_custom_domain.test", &project, &domain),
+					resource.TestCheckResourceAttr(
+						"deploy_custom_domain.test", "domain_name", randomDomain,
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_custom_domain.test", "is_wildcard", "true",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_custom_domain.test", "records.0.domain_name", randomDomain,
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_custom_domain.test", "records.2.domain_name", fmt.Sprintf("apps-%s.example.org", randomID),
+					),
+				),
+			},
+		},
+	})
+}
+
 func testAccCustomDomainCheckExists(rn string, p *client.Project, d *client.Domain) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[rn]
@@ -105,3 +171,27 @@ resource "deploy_custom_domain" "test" {
   domain_name = "foo-%s.example.org"
 }
 `
+
+const testAccCustomDomainConfig_normalized = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+}
+
+resource "deploy_custom_domain" "test" {
+  project_id  = deploy_project.test.id
+  domain_name = "Foo-%s.Example.org."
+}
+`
+
+const testAccCustomDomainConfig_wildcard = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+}
+
+resource "deploy_custom_domain" "test" {
+  project_id  = deploy_project.test.id
+  domain_name = "*.apps-%s.example.org"
+}
+`
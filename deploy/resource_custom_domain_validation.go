@@ -3,6 +3,7 @@ package deploy
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/wperron/terraform-deploy-provider/client"
@@ -20,9 +21,11 @@ func resourceCustomDomainValidation() *schema.Resource {
 				Required: true,
 			},
 			"custom_domain": {
-				Type:     schema.TypeString,
-				ForceNew: true,
-				Required: true,
+				Type:             schema.TypeString,
+				ForceNew:         true,
+				Required:         true,
+				ValidateFunc:     validateNonWildcardDomainName,
+				DiffSuppressFunc: suppressEquivalentDomainName,
 			},
 		},
 	}
@@ -31,7 +34,18 @@ func resourceCustomDomainValidation() *schema.Resource {
 func createCustomDomainValidation(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
 	projectID := d.Get("project_id").(string)
-	domainName := d.Get("custom_domain").(string)
+	domainName, err := normalizeDomainName(d.Get("custom_domain").(string))
+	if err != nil {
+		return err
+	}
+	// the domain name may not have been known when the configuration was
+	// validated
+	if isWildcardDomainName(domainName) {
+		return fmt.Errorf("error validating %s: %w", domainName, errWildcardCertificate)
+	}
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
 	domain, err := c.GetDomain(projectID, domainName)
 	if err != nil {
 		return err
@@ -52,8 +66,14 @@ func createCustomDomainValidation(d *schema.ResourceData, meta interface{}) erro
 func readCustomDomainValidation(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
 	projectID := d.Get("project_id").(string)
-	domainName := d.Get("custom_domain").(string)
-	domain, err := c.GetDomain(projectID, domainName)
+	domainName, err := normalizeDomainName(d.Get("custom_domain").(string))
+	if err != nil {
+		return err
+	}
+
+	// the validation and certificates of the domain change on Deploy's side,
+	// they must not be read from the cache of the domains
+	domain, err := c.GetDomainUncached(projectID, domainName)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,159 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+func resourceEnvVar() *schema.Resource {
+	return &schema.Resource{
+		Create: createEnvVar,
+		Read:   readEnvVar,
+		Update: updateEnvVar,
+		Delete: deleteEnvVar,
+		Importer: &schema.ResourceImporter{
+			StateContext: importEnvVar,
+		},
+		Schema: map[string]*schema.Schema{
+			"project_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"key": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateEnvVarKey,
+			},
+			"value": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				ValidateFunc: validateEnvVarValue,
+				ExactlyOneOf: []string{"value", "secret_value"},
+			},
+			"secret_value": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        true,
+				StateFunc:        hashSecretValue,
+				DiffSuppressFunc: suppressSecretValueDiff,
+				ValidateFunc:     validation.All(validateEnvVarValue, validateSecretValue),
+				ExactlyOneOf:     []string{"value", "secret_value"},
+			},
+		},
+	}
+}
+
+func createEnvVar(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID := d.Get("project_id").(string)
+	key := d.Get("key").(string)
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
+	if err := c.SetEnvVar(projectID, key, envVarValue(d)); err != nil {
+		return err
+	}
+
+	d.SetId(envVarID(projectID, key))
+	return readEnvVar(d, meta)
+}
+
+func readEnvVar(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID, key, err := parseEnvVarID(d.Id())
+	if err != nil {
+		return err
+	}
+
+	project, err := c.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+
+	// The API only returns the keys of the environment variables, the value
+	// cannot be read back.
+	if !includes(project.EnvVars, key) {
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("project_id", projectID); err != nil {
+		return err
+	}
+	if err := d.Set("key", key); err != nil {
+		return err
+	}
+	return nil
+}
+
+func updateEnvVar(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID := d.Get("project_id").(string)
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
+	if err := c.SetEnvVar(projectID, d.Get("key").(string), envVarValue(d)); err != nil {
+		return err
+	}
+
+	return readEnvVar(d, meta)
+}
+
+func deleteEnvVar(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID := d.Get("project_id").(string)
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
+	return c.DeleteEnvVar(projectID, d.Get("key").(string))
+}
+
+func importEnvVar(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, _, err := parseEnvVarID(d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// envVarValue returns the value of the environment variable, whether it is
+// set in `value` or `secret_value`. Only the hash of `secret_value` is kept in
+// state, but the plain value is available while applying the configuration.
+func envVarValue(d *schema.ResourceData) string {
+	if v, ok := d.GetOk("secret_value"); ok {
+		return v.(string)
+	}
+	return d.Get("value").(string)
+}
+
+// envVarID returns the ID of a `deploy_env_var` resource, in the form
+// `<project_id>/<key>`.
+func envVarID(projectID, key string) string {
+	return fmt.Sprintf("%s/%s", projectID, key)
+}
+
+func parseEnvVarID(id string) (string, string, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("unexpected format of ID (%s), expected <project_id>/<key>", id)
+	}
+	return parts[0], parts[1], nil
+}
+
+// includes returns whether the slice contains the string.
+func includes(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
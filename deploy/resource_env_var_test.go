@@ -0,0 +1,129 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+func TestAccEnvVar_basic(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccEnvVarConfig_basic, randomID, "foo", "bar")
+	updated := fmt.Sprintf(testAccEnvVarConfig_basic, randomID, "fruit", "banana")
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_env_var.test", "key", "SECRET",
+					),
+					testAccEnvVarCheckKeys(&project, []string{"foo", "SECRET"}, nil),
+				),
+			},
+			{
+				// Changing the project's own variables must not remove the
+				// variable managed by the `deploy_env_var` resource.
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccEnvVarCheckKeys(&project, []string{"fruit", "SECRET"}, []string{"foo"}),
+				),
+			},
+			{
+				ResourceName:            "deploy_env_var.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"value", "secret_value"},
+			},
+		},
+	})
+}
+
+func TestAccEnvVar_secretValue(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccEnvVarConfig_secretValue, randomID),
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccCheckSecretValue("deploy_env_var.test", "secret_value", "hunter2"),
+					testAccEnvVarCheckKeys(&project, []string{"SECRET"}, nil),
+				),
+			},
+		},
+	})
+}
+
+func testAccEnvVarCheckKeys(p *client.Project, present []string, absent []string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, k := range present {
+			if !includes(p.EnvVars, k) {
+				return fmt.Errorf("expected project to have a %s environment variable", k)
+			}
+		}
+		for _, k := range absent {
+			if includes(p.EnvVars, k) {
+				return fmt.Errorf("expected project not to have a %s environment variable", k)
+			}
+		}
+		return nil
+	}
+}
+
+// testAccCheckSecretValue checks that the attribute of the resource is a hash
+// of the value.
+func testAccCheckSecretValue(name, key, value string) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(name, key, func(hash string) error {
+		if !verifySecretValue(hash, value) {
+			return fmt.Errorf("expected %q to be a hash of %q", hash, value)
+		}
+		return nil
+	})
+}
+
+const testAccEnvVarConfig_basic = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+
+  env_var {
+    key   = "%s"
+    value = "%s"
+  }
+}
+
+resource "deploy_env_var" "test" {
+  project_id = deploy_project.test.id
+  key        = "SECRET"
+  value      = "hunter2"
+}
+`
+
+const testAccEnvVarConfig_secretValue = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+}
+
+resource "deploy_env_var" "test" {
+  project_id   = deploy_project.test.id
+  key          = "SECRET"
+  secret_value = "hunter2"
+}
+`
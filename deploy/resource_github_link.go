@@ -0,0 +1,185 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+func resourceGitHubLink() *schema.Resource {
+	s := gitHubLinkSchema()
+	s["project_id"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	}
+
+	return &schema.Resource{
+		Create: createGitHubLink,
+		Read:   readGitHubLink,
+		Update: updateGitHubLink,
+		Delete: deleteGitHubLink,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: s,
+	}
+}
+
+// gitHubLinkSchema returns the schema of a GitHub link, shared by the
+// `deploy_github_link` resource and the `github_link` block of the
+// `deploy_project` resource.
+func gitHubLinkSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"organization": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"repo": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"entrypoint": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"production_branch": {
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+		},
+		"preview_deployments": {
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  true,
+		},
+		"mode": {
+			Type:     schema.TypeString,
+			Optional: true,
+			Default:  client.GitHubModeAutomatic,
+			ValidateFunc: validation.StringInSlice([]string{
+				client.GitHubModeAutomatic,
+				client.GitHubModeGitHubActions,
+			}, false),
+		},
+		"install_command": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"build_command": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"repository_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+	}
+}
+
+// gitHubLinkResourceMap returns the GitHub link attributes of a
+// `deploy_github_link` resource in the same form as a `github_link` block.
+func gitHubLinkResourceMap(d *schema.ResourceData) map[string]interface{} {
+	tfMap := map[string]interface{}{}
+	for k := range gitHubLinkSchema() {
+		tfMap[k] = d.Get(k)
+	}
+	return tfMap
+}
+
+func createGitHubLink(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectID := d.Get("project_id").(string)
+	projectMutexKV.Lock(projectID)
+	defer projectMutexKV.Unlock(projectID)
+
+	if _, err := c.LinkProject(expandGitHubLink(projectID, gitHubLinkResourceMap(d))); err != nil {
+		return err
+	}
+
+	d.SetId(projectID)
+	return readGitHubLink(d, meta)
+}
+
+func readGitHubLink(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	project, err := c.GetProject(d.Id())
+	if err != nil {
+		return err
+	}
+
+	if project.Git == nil {
+		// the project was unlinked outside of Terraform
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("project_id", project.ID); err != nil {
+		return err
+	}
+	for k, v := range flattenGitHubLink(project.Git)[0] {
+		if err := d.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateGitHubLink(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectMutexKV.Lock(d.Id())
+	defer projectMutexKV.Unlock(d.Id())
+
+	if _, err := c.LinkProject(expandGitHubLink(d.Id(), gitHubLinkResourceMap(d))); err != nil {
+		return err
+	}
+
+	return readGitHubLink(d, meta)
+}
+
+func deleteGitHubLink(d *schema.ResourceData, meta interface{}) error {
+	c := meta.(*client.Client)
+	projectMutexKV.Lock(d.Id())
+	defer projectMutexKV.Unlock(d.Id())
+
+	return c.Unlink(d.Id())
+}
+
+// expandGitHubLink returns the request used to link the given project to the
+// configured GitHub repository.
+func expandGitHubLink(projectID string, tfMap map[string]interface{}) client.LinkProjectRequest {
+	return client.LinkProjectRequest{
+		ProjectID:          projectID,
+		Organization:       tfMap["organization"].(string),
+		Repo:               tfMap["repo"].(string),
+		Entrypoint:         tfMap["entrypoint"].(string),
+		ProductionBranch:   tfMap["production_branch"].(string),
+		PreviewDeployments: tfMap["preview_deployments"].(bool),
+		Mode:               tfMap["mode"].(string),
+		InstallCommand:     tfMap["install_command"].(string),
+		BuildCommand:       tfMap["build_command"].(string),
+	}
+}
+
+// flattenGitHubLink returns the Terraform representation of a GitHub link, as
+// used by the `github_link` block of the `deploy_project` resource.
+func flattenGitHubLink(link *client.GitHubLink) []map[string]interface{} {
+	if link == nil {
+		return []map[string]interface{}{}
+	}
+
+	return []map[string]interface{}{
+		{
+			"organization":        link.Repository.Owner,
+			"repo":                link.Repository.Name,
+			"entrypoint":          link.Entrypoint,
+			"production_branch":   link.ProductionBranch,
+			"preview_deployments": link.PreviewDeployments,
+			"mode":                link.Mode,
+			"install_command":     link.InstallCommand,
+			"build_command":       link.BuildCommand,
+			"repository_id":       link.Repository.ID,
+		},
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+func TestAccGitHubLink_basic(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccGitHubLinkConfig_basic, randomID)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccGitHubLinkCheckExists("deploy_github_link.test", &project),
+					resource.TestCheckResourceAttrPair(
+						"deploy_github_link.test", "project_id", "deploy_project.test", "id",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "organization", "wperron",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "repo", "terraform-deploy-provider",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "entrypoint", "/deploy/testdata/main.ts",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "production_branch", "main",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "preview_deployments", "false",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_github_link.test", "mode", "automatic",
+					),
+					resource.TestCheckResourceAttrSet(
+						"deploy_github_link.test", "repository_id",
+					),
+					resource.TestCheckNoResourceAttr(
+						"deploy_project.test", "github_link.#",
+					),
+				),
+			},
+			{
+				// The project does not declare a `github_link` block, linking it
+				// through the separate resource must not produce a diff.
+				Config:   config,
+				PlanOnly: true,
+			},
+			{
+				ResourceName:      "deploy_github_link.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccGitHubLinkCheckExists(rn string, p *client.Project) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[rn]
+		if !ok {
+			return fmt.Errorf("resource not found: %s", rn)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("resource id not set")
+		}
+		client := testAccProvider.Meta().(*client.Client)
+		project, err := client.GetProject(rs.Primary.ID)
+		if err != nil {
+			return fmt.Errorf("error getting project: %s", err)
+		}
+		if project.Git == nil {
+			return fmt.Errorf("project %s is not linked to a GitHub repository", project.ID)
+		}
+		*p = project
+		return nil
+	}
+}
+
+const testAccGitHubLinkConfig_basic = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+}
+
+resource "deploy_github_link" "test" {
+  project_id   = deploy_project.test.id
+  organization = "wperron"
+  repo         = "terraform-deploy-provider"
+  entrypoint   = "/deploy/testdata/main.ts"
+
+  production_branch   = "main"
+  preview_deployments = false
+}
+`
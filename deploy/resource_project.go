@@ -2,6 +2,13 @@
 package deploy
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/wperron/terraform-deploy-provider/client"
 )
@@ -14,150 +21,270 @@ func resourceProject() *schema.Resource {
 		Delete: deleteProject,
 		Exists: existsProject,
 		// TODO(wperron) implement Importer
+		CustomizeDiff: customizeDiffProject,
+		SchemaVersion: 2,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceProjectV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceProjectStateUpgradeV0,
+			},
+			{
+				Version: 1,
+				Type:    resourceProjectV1().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceProjectStateUpgradeV1,
+			},
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+		},
 		Schema: map[string]*schema.Schema{
 			"project_id": {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
 			"name": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateProjectName,
 			},
 			"source_url": {
 				Type:          schema.TypeString,
 				Optional:      true,
-				ConflictsWith: []string{"github_link"},
+				ConflictsWith: []string{"github_link", "source_file", "source_dir"},
 			},
-			"github_link": {
+			"source_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_url", "source_dir"},
+			},
+			"source_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"source_dir": {
 				Type:          schema.TypeList,
 				MaxItems:      1,
 				Optional:      true,
-				ConflictsWith: []string{"source_url"},
+				ConflictsWith: []string{"github_link", "source_url", "source_file"},
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
-						"organization": {
+						"path": {
 							Type:     schema.TypeString,
 							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
-						"repo": {
+						"entrypoint": {
 							Type:     schema.TypeString,
 							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
-						"entrypoint": {
+						"import_map": {
 							Type:     schema.TypeString,
-							Required: true,
+							Optional: true,
+						},
+						"include": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"exclude": {
+							Type:     schema.TypeList,
+							Optional: true,
 							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
 					},
 				},
 			},
+			"source_dir_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"github_link": {
+				Type:          schema.TypeList,
+				MaxItems:      1,
+				Optional:      true,
+				ConflictsWith: []string{"source_url", "source_file", "source_dir"},
+				Elem: &schema.Resource{
+					Schema: gitHubLinkSchema(),
+				},
+			},
+			"health_check": {
+				Type:     schema.TypeList,
+				MaxItems: 1,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "/",
+						},
+						"expected_status": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  200,
+						},
+						"expected_body": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
 			"production_deployment": {
 				Type:     schema.TypeList,
 				Computed: true,
-				Optional: true,
-				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"id": {
 							Type:     schema.TypeString,
-							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
 						},
 						"url": {
 							Type:     schema.TypeString,
-							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
 						},
 						"domain_mappings": {
 							Type:     schema.TypeList,
-							Required: true,
-							Elem: &schema.Schema{
-								Type: schema.TypeMap,
-								Elem: &schema.Schema{Type: schema.TypeString},
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"domain": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"updated_at": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"created_at": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
 							},
 						},
 						"related_commit": {
 							Type:     schema.TypeList,
-							MaxItems: 1,
-							Optional: true,
+							Computed: true,
 							Elem: &schema.Resource{
 								Schema: map[string]*schema.Schema{
 									"hash": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 									"message": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 									"author_name": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 									"author_email": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 									"author_github_username": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 									"url": {
 										Type:     schema.TypeString,
-										Required: true,
-										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
 									},
 								},
 							},
 						},
-						"env_var": {
+						"env_var_keys": {
 							Type:     schema.TypeList,
-							Optional: true,
+							Computed: true,
 							Elem:     &schema.Schema{Type: schema.TypeString},
 						},
 						"updated_at": {
 							Type:     schema.TypeString,
-							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
 						},
 						"created_at": {
 							Type:     schema.TypeString,
-							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
 						},
 					},
 				},
 			},
+			"default_domain": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 			"has_production_deployment": {
 				Type:     schema.TypeBool,
 				Computed: true,
 			},
 			"env_var": {
-				Type:     schema.TypeList,
+				Type:     schema.TypeSet,
 				Optional: true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"key": {
-							Type:     schema.TypeString,
-							Required: true,
-							Elem:     &schema.Schema{Type: schema.TypeString},
+							Type:         schema.TypeString,
+							Required:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateEnvVarKey,
 						},
 						"value": {
-							Type:      schema.TypeString,
-							Required:  true,
-							Sensitive: true,
-							Elem:      &schema.Schema{Type: schema.TypeString},
+							Type:         schema.TypeString,
+							Required:     true,
+							Sensitive:    true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateEnvVarValue,
 						},
 					},
 				},
 			},
+			"env_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"env_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"env_file_keys": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"secret_env_var": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Set:      secretEnvVarSetHash,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateEnvVarKey,
+						},
+						"value": {
+							Type:         schema.TypeString,
+							Required:     true,
+							Sensitive:    true,
+							StateFunc:    blankSecretValue,
+							ValidateFunc: validateEnvVarValue,
+						},
+					},
+				},
+			},
+			"secret_env_var_hashes": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 		},
 	}
 }
@@ -165,11 +292,9 @@ func resourceProject() *schema.Resource {
 func createProject(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
 	name := d.Get("name").(string)
-	vars := make(client.NewEnvVars)
-	tmp := d.Get("env_var").([]interface{})
-	for _, v := range tmp {
-		keyval := v.(map[string]interface{})
-		vars[keyval["key"].(string)] = keyval["value"].(string)
+	vars, err := projectEnvVars(d)
+	if err != nil {
+		return err
 	}
 
 	project, err := c.CreateProject(name, vars)
@@ -177,27 +302,15 @@ func createProject(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	if source, ok := d.GetOk("source_url"); ok {
-		if _, err := c.NewProjectDeployment(project.ID, client.NewDeploymentRequest{
-			URL:        source.(string),
-			Production: true,
-		}); err != nil {
-			return err
-		}
-	} else if gh, ok := d.GetOk("github_link"); ok {
-		ghLinkList := gh.([]interface{})
-		ghLink := ghLinkList[0].(map[string]interface{})
-		if _, err := c.LinkProject(client.LinkProjectRequest{
-			ProjectID:    project.ID,
-			Organization: ghLink["organization"].(string),
-			Repo:         ghLink["repo"].(string),
-			Entrypoint:   ghLink["entrypoint"].(string),
-		}); err != nil {
-			return err
-		}
+	d.SetId(project.ID)
+	if err := setSecretEnvVarHashes(d); err != nil {
+		return err
+	}
+
+	if err := deployProjectSource(d, c, projectSourceModeOfData(d), nil, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
 	}
 
-	d.SetId(project.ID)
 	return readProject(d, meta)
 }
 
@@ -212,7 +325,9 @@ func readProject(d *schema.ResourceData, meta interface{}) error {
 		if err := d.Set("production_deployment", productionDeploymentToTerraformSchema(project.ProductionDeployment)); err != nil {
 			return err
 		}
-		if source, ok := d.GetOk("source_url"); ok && source != project.ProductionDeployment.URL {
+		// When the project is linked to GitHub the production deployment's URL
+		// points to the repository, it must not be read back as the source URL.
+		if source, ok := d.GetOk("source_url"); ok && project.Git == nil && source != project.ProductionDeployment.URL {
 			if err := d.Set("source_url", project.ProductionDeployment.URL); err != nil {
 				return err
 			}
@@ -221,15 +336,18 @@ func readProject(d *schema.ResourceData, meta interface{}) error {
 	if err := d.Set("has_production_deployment", project.HasProductionDeployment); err != nil {
 		return err
 	}
+	if err := d.Set("default_domain", projectDefaultDomain(project.Name)); err != nil {
+		return err
+	}
+	if err := d.Set("url", projectURL(project.Name)); err != nil {
+		return err
+	}
 
-	if project.Git != nil {
-		if err := d.Set("github_link", []map[string]interface{}{
-			{
-				"organization": project.Git.Repository.Owner,
-				"repo":         project.Git.Repository.Name,
-				"entrypoint":   project.Git.Entrypoint,
-			},
-		}); err != nil {
+	// The GitHub link can also be managed by a separate `deploy_github_link`
+	// resource, in which case it must not be tracked here or the project
+	// would try to unlink it on the next apply.
+	if _, ok := d.GetOk("github_link"); ok {
+		if err := d.Set("github_link", flattenGitHubLink(project.Git)); err != nil {
 			return err
 		}
 	}
@@ -238,6 +356,9 @@ func readProject(d *schema.ResourceData, meta interface{}) error {
 
 func updateProject(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
+	projectMutexKV.Lock(d.Id())
+	defer projectMutexKV.Unlock(d.Id())
+
 	if d.HasChange("name") {
 		name := d.Get("name").(string)
 		if err := c.UpdateProject(d.Id(), name); err != nil {
@@ -245,56 +366,376 @@ func updateProject(d *schema.ResourceData, meta interface{}) error {
 		}
 	}
 
-	if d.HasChange("env_var") {
-		vars := make(client.NewEnvVars)
-		tmp := d.Get("env_var").([]interface{})
-		for _, v := range tmp {
-			keyval := v.(map[string]interface{})
-			vars[keyval["key"].(string)] = keyval["value"].(string)
+	// The secret values are not in state, so their changes don't show up in
+	// HasChanges. projectEnvVarsPatch compares them with their hashes instead.
+	envVars, err := projectEnvVarsPatch(d)
+	if err != nil {
+		return err
+	}
+
+	if err := updateProjectSource(d, c, envVars); err != nil {
+		return err
+	}
+
+	if err := setSecretEnvVarHashes(d); err != nil {
+		return err
+	}
+	return readProject(d, meta)
+}
+
+// customizeDiffProject computes the hashes of the project's local sources so
+// that a change to their content shows up in the plan, even though their paths
+// are the same, validates the environment variables, and marks the production
+// deployment as changing when the update creates a new one.
+var customizeDiffProject = customdiff.All(
+	customizeDiffSourceFile,
+	customizeDiffSourceDir,
+	customizeDiffEnvVars,
+	customizeDiffEnvFile,
+	customizeDiffSecretEnvVars,
+	customizeDiffProductionDeployment,
+	customizeDiffDefaultDomain,
+)
+
+// projectDomainSuffix is the parent domain of the default domain of every
+// project.
+const projectDomainSuffix = ".deno.dev"
+
+// projectDefaultDomain returns the domain a project is served on by default,
+// which is derived from its name.
+func projectDefaultDomain(name string) string {
+	return name + projectDomainSuffix
+}
+
+// projectURL returns the URL a project is served on by default.
+func projectURL(name string) string {
+	return "https://" + projectDefaultDomain(name)
+}
+
+// customizeDiffDefaultDomain computes the default domain and URL of the
+// project from its name, so they are known in the plan.
+func customizeDiffDefaultDomain(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("name") {
+		if err := d.SetNewComputed("default_domain"); err != nil {
+			return err
 		}
+		return d.SetNewComputed("url")
+	}
+
+	name := d.Get("name").(string)
+	if d.Get("default_domain").(string) == projectDefaultDomain(name) {
+		return nil
+	}
+	if err := d.SetNew("default_domain", projectDefaultDomain(name)); err != nil {
+		return err
+	}
+	return d.SetNew("url", projectURL(name))
+}
+
+// customizeDiffProductionDeployment marks the production deployment as
+// changing when the update creates a new one, so that the resources depending
+// on it know they will change as well.
+//
+// It must run after the other CustomizeDiff functions since it depends on the
+// hashes of the project's local sources, env file and secret values.
+func customizeDiffProductionDeployment(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	// the production deployment of a new project is always unknown
+	if d.Id() == "" {
+		return nil
+	}
 
-		if err := c.UpdateEnvVars(d.Id(), vars); err != nil {
+	if projectSourceDeploys(d) {
+		// Linking a repository doesn't take environment variables, they are
+		// updated once it is linked, which deploys the repository again.
+		if _, to, _ := projectSourceChange(d); to == projectSourceGitHub && projectEnvVarsChange(d) {
+			tflog.Warn(withLogMasking(ctx), "linking the GitHub repository and updating the environment variables creates two deployments", map[string]interface{}{
+				"project_id": d.Id(),
+			})
+		}
+		if err := d.SetNewComputed("production_deployment"); err != nil {
 			return err
 		}
+		if !d.Get("has_production_deployment").(bool) {
+			return d.SetNewComputed("has_production_deployment")
+		}
+		return nil
 	}
 
-	if d.HasChange("source_url") {
-		if source, ok := d.GetOk("source_url"); ok {
-			if _, err := c.NewProjectDeployment(d.Id(), client.NewDeploymentRequest{
-				URL:        source.(string),
-				Production: true,
-			}); err != nil {
-				return err
-			}
+	// Changing the environment variables redeploys the production deployment,
+	// if there is one.
+	if d.Get("has_production_deployment").(bool) && projectEnvVarsChange(d) {
+		return d.SetNewComputed("production_deployment")
+	}
+	return nil
+}
+
+// projectEnvVarsChange reports whether the plan changes the environment
+// variables of the project.
+func projectEnvVarsChange(d *schema.ResourceDiff) bool {
+	for _, k := range []string{"env_var", "secret_env_var", "env_file_hash", "env_file_keys"} {
+		if d.HasChange(k) {
+			return true
 		}
 	}
+	return !d.NewValueKnown("secret_env_var_hashes")
+}
 
-	if d.HasChange("github_link") {
-		if gh, ok := d.GetOk("github_link"); ok {
-			ghLinkList := gh.([]interface{})
-			ghLink := ghLinkList[0].(map[string]interface{})
-			if _, err := c.LinkProject(client.LinkProjectRequest{
-				ProjectID:    d.Id(),
-				Organization: ghLink["organization"].(string),
-				Repo:         ghLink["repo"].(string),
-				Entrypoint:   ghLink["entrypoint"].(string),
-			}); err != nil {
-				return err
+// customizeDiffSecretEnvVars marks the hashes of the secret values as changing
+// when a value no longer matches its hash in state. The hashes have random
+// salts, so each value is hashed again with the salt of its hash in state to
+// compare them.
+func customizeDiffSecretEnvVars(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	config := d.GetRawConfig()
+	if config.IsNull() {
+		return nil
+	}
+	vars, ok := configSecretEnvVars(config)
+	if !ok {
+		return d.SetNewComputed("secret_env_var_hashes")
+	}
+
+	old := d.Get("secret_env_var_hashes").(map[string]interface{})
+	if len(old) != len(vars) {
+		return d.SetNewComputed("secret_env_var_hashes")
+	}
+	for k, v := range vars {
+		if hash, ok := old[k].(string); !ok || !verifySecretValue(hash, v) {
+			return d.SetNewComputed("secret_env_var_hashes")
+		}
+	}
+	return nil
+}
+
+// projectSecretEnvVars returns the values of the `secret_env_var` blocks.
+// Only their keys are in state, the values are read from the configuration.
+func projectSecretEnvVars(d *schema.ResourceData) client.NewEnvVars {
+	if config := d.GetRawConfig(); !config.IsNull() {
+		if vars, ok := configSecretEnvVars(config); ok {
+			return vars
+		}
+	}
+	return expandEnvVars(d.Get("secret_env_var").(*schema.Set).List())
+}
+
+// setSecretEnvVarHashes stores the hashes of the secret values in state,
+// keeping the hashes of the values that did not change.
+func setSecretEnvVarHashes(d *schema.ResourceData) error {
+	old, _ := d.GetChange("secret_env_var_hashes")
+	hashes := hashSecretEnvVars(old.(map[string]interface{}), projectSecretEnvVars(d))
+	return d.Set("secret_env_var_hashes", hashes)
+}
+
+// projectEnvVars returns the values of all the environment variables declared
+// in the project. The variables of the `env_var` and `secret_env_var` blocks
+// take precedence over the ones of the `env_file`.
+func projectEnvVars(d *schema.ResourceData) (client.NewEnvVars, error) {
+	vars, err := readEnvFile(d.Get("env_file").(string), d.Get("env_file_hash").(string))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range expandEnvVars(d.Get("env_var").(*schema.Set).List()) {
+		vars[k] = v
+	}
+	for k, v := range projectSecretEnvVars(d) {
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// projectEnvVarsPatch returns the partial update of the environment variables
+// that were added, modified or removed from the `env_var` and `secret_env_var`
+// blocks and the `env_file`.
+//
+// The environment variables that were never declared in the project are left
+// untouched, so they can be managed by `deploy_env_var` resources.
+func projectEnvVarsPatch(d *schema.ResourceData) (client.EnvVarsPatch, error) {
+	// Neither the secret values nor the values of the env file are in state,
+	// the variables are compared using the hashes of the secret values and the
+	// hash of the env file instead.
+	oldVars, newVars := make(client.NewEnvVars), make(client.NewEnvVars)
+	o, n := d.GetChange("env_file_keys")
+	oh, nh := d.GetChange("env_file_hash")
+	for _, k := range o.(*schema.Set).List() {
+		oldVars[k.(string)] = "env_file:" + oh.(string)
+	}
+	for _, k := range n.(*schema.Set).List() {
+		newVars[k.(string)] = "env_file:" + nh.(string)
+	}
+
+	o, n = d.GetChange("env_var")
+	for k, v := range expandEnvVars(o.(*schema.Set).List()) {
+		oldVars[k] = v
+	}
+	for k, v := range expandEnvVars(n.(*schema.Set).List()) {
+		newVars[k] = v
+	}
+
+	o, _ = d.GetChange("secret_env_var_hashes")
+	oldHashes := o.(map[string]interface{})
+	for k, v := range oldHashes {
+		oldVars[k] = v.(string)
+	}
+	for k, v := range hashSecretEnvVars(oldHashes, projectSecretEnvVars(d)) {
+		newVars[k] = v.(string)
+	}
+
+	patch := diffEnvVars(oldVars, newVars)
+	if len(patch) == 0 {
+		return nil, nil
+	}
+
+	values, err := projectEnvVars(d)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range patch {
+		if v != nil {
+			v := values[k]
+			patch[k] = &v
+		}
+	}
+
+	return patch, nil
+}
+
+// diffEnvVars returns the patch turning the old environment variables into the
+// new ones.
+func diffEnvVars(oldVars, newVars client.NewEnvVars) client.EnvVarsPatch {
+	patch := client.EnvVarsPatch{}
+	for k := range oldVars {
+		if _, ok := newVars[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, v := range newVars {
+		if old, ok := oldVars[k]; !ok || old != v {
+			v := v
+			patch[k] = &v
+		}
+	}
+	return patch
+}
+
+// customizeDiffEnvVars rejects `env_var` and `secret_env_var` blocks declaring
+// the same key more than once. Blocks with the same key and value are already
+// merged by the sets.
+//
+// The `secret_env_var` set is hashed on the keys only, which would merge blocks
+// with the same key and different values, so their keys are read from the
+// configuration instead.
+func customizeDiffEnvVars(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("env_var") || !d.NewValueKnown("secret_env_var") {
+		return nil
+	}
+	var keys []string
+	for _, v := range d.Get("env_var").(*schema.Set).List() {
+		keys = append(keys, v.(map[string]interface{})["key"].(string))
+	}
+	if config := d.GetRawConfig(); config.IsNull() {
+		for _, v := range d.Get("secret_env_var").(*schema.Set).List() {
+			keys = append(keys, v.(map[string]interface{})["key"].(string))
+		}
+	} else {
+		secretKeys, ok := configSecretEnvVarKeys(config)
+		if !ok {
+			return nil
+		}
+		keys = append(keys, secretKeys...)
+	}
+
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			return fmt.Errorf("duplicate environment variable %q: each key can only be declared once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// customizeDiffEnvFile parses the `env_file` so that syntax errors are
+// reported at plan time, and computes the hash and keys of the file so that
+// a change to its content shows up in the plan.
+func customizeDiffEnvFile(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("env_file") {
+		if err := d.SetNewComputed("env_file_hash"); err != nil {
+			return err
+		}
+		return d.SetNewComputed("env_file_keys")
+	}
+
+	hash, keys := "", []interface{}{}
+	if path, ok := d.GetOk("env_file"); ok {
+		content, err := ioutil.ReadFile(path.(string))
+		if err != nil {
+			return fmt.Errorf("error reading env file: %w", err)
+		}
+		vars, err := parseDotenv(string(content))
+		if err != nil {
+			return fmt.Errorf("error parsing env file %s: %w", path, err)
+		}
+		hash = contentHash(content)
+		for k, v := range vars {
+			if err := checkEnvVarKey(k); err != nil {
+				return fmt.Errorf("error in env file %s: key %q %w", path, k, err)
 			}
-		} else if o, n := d.GetChange("github_link"); len(o.([]interface{})) == 1 && len(n.([]interface{})) == 0 {
-			// if the new value is empty but the old value is not, it means the
-			// block was removed and the repo should be unlinked
-			if err := c.Unlink(d.Id()); err != nil {
-				return err
+			if err := checkEnvVarValue(v); err != nil {
+				return fmt.Errorf("error in env file %s: value of %q %w", path, k, err)
 			}
+			keys = append(keys, k)
 		}
 	}
 
-	return readProject(d, meta)
+	if hash != d.Get("env_file_hash").(string) {
+		if err := d.SetNew("env_file_hash", hash); err != nil {
+			return err
+		}
+		return d.SetNew("env_file_keys", schema.NewSet(schema.HashString, keys))
+	}
+	return nil
+}
+
+// readEnvFile reads and parses the `env_file`. The expectedHash is the hash of
+// the file computed during the plan, reading fails if the file changed since
+// then.
+func readEnvFile(path, expectedHash string) (client.NewEnvVars, error) {
+	vars := make(client.NewEnvVars)
+	if path == "" {
+		return vars, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file: %w", err)
+	}
+	if hash := contentHash(content); expectedHash != "" && hash != expectedHash {
+		return nil, fmt.Errorf("env file %s changed since the plan was created", path)
+	}
+
+	parsed, err := parseDotenv(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing env file %s: %w", path, err)
+	}
+	for k, v := range parsed {
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+func expandEnvVars(l []interface{}) client.NewEnvVars {
+	vars := make(client.NewEnvVars)
+	for _, v := range l {
+		keyval := v.(map[string]interface{})
+		vars[keyval["key"].(string)] = keyval["value"].(string)
+	}
+	return vars
 }
 
 func deleteProject(d *schema.ResourceData, meta interface{}) error {
 	c := meta.(*client.Client)
+	projectMutexKV.Lock(d.Id())
+	defer projectMutexKV.Unlock(d.Id())
+
 	return c.DeleteProject(d.Id())
 }
 
@@ -337,14 +778,8 @@ func productionDeploymentToTerraformSchema(depl *client.Deployment) []interface{
 		}
 	}
 
-	// vars := []map[string]interface{}{}
-	// for k, v := range depl.EnvVars {
-	// 	vars = append(vars, map[string]interface{}{
-	// 		"key":   k,
-	// 		"value": v,
-	// 	})
-	// }
-	tfMap["env_var"] = depl.EnvVars
+	// the values of the environment variables are never returned by the API
+	tfMap["env_var_keys"] = []string(depl.EnvVars)
 	tfMap["updated_at"] = depl.UpdatedAt
 	tfMap["created_at"] = depl.CreatedAt
 
@@ -0,0 +1,614 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// resourceProjectV0 is the schema of the `deploy_project` resource before
+// `env_var` was turned into a set. It is a frozen copy of the schema at that
+// version: it must not reference the current schema or its helpers, whose
+// changes would alter the shape the old states are decoded with.
+func resourceProjectV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"project_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"source_url": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_file", "source_dir"},
+			},
+			"source_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_url", "source_dir"},
+			},
+			"source_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"source_dir": {
+				Type:          schema.TypeList,
+				MaxItems:      1,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_url", "source_file"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"entrypoint": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"import_map": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"include": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"exclude": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"source_dir_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"github_link": {
+				Type:          schema.TypeList,
+				MaxItems:      1,
+				Optional:      true,
+				ConflictsWith: []string{"source_url", "source_file", "source_dir"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"organization": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"repo": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"entrypoint": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"production_branch": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"preview_deployments": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"mode": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "automatic",
+							ValidateFunc: validation.StringInSlice([]string{"automatic", "github_actions"}, false),
+						},
+						"install_command": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"build_command": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"repository_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"health_check": {
+				Type:     schema.TypeList,
+				MaxItems: 1,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "/",
+						},
+						"expected_status": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  200,
+						},
+						"expected_body": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"production_deployment": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"url": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"domain_mappings": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeMap,
+								Elem: &schema.Schema{Type: schema.TypeString},
+							},
+						},
+						"related_commit": {
+							Type:     schema.TypeList,
+							MaxItems: 1,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"hash": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"message": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_name": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_email": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_github_username": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"url": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+								},
+							},
+						},
+						"env_var": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"updated_at": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"has_production_deployment": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"env_var": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"value": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+							Elem:      &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// resourceProjectStateUpgradeV0 converts the `env_var` list to a set. Since
+// keys were collapsed when sending the variables to Deploy, only the last
+// value of a duplicate key is kept.
+func resourceProjectStateUpgradeV0(_ context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	if rawState == nil {
+		return rawState, nil
+	}
+
+	l, ok := rawState["env_var"].([]interface{})
+	if !ok {
+		return rawState, nil
+	}
+
+	index := map[string]int{}
+	vars := []interface{}{}
+	for _, v := range l {
+		keyval, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := keyval["key"].(string)
+		if i, ok := index[key]; ok {
+			vars[i] = keyval
+			continue
+		}
+		index[key] = len(vars)
+		vars = append(vars, keyval)
+	}
+	rawState["env_var"] = vars
+
+	return rawState, nil
+}
+
+// resourceProjectV1 is the schema of the `deploy_project` resource before the
+// attributes of `production_deployment` were typed: `domain_mappings` was a
+// list of maps and the keys of the environment variables were in `env_var`.
+// Like resourceProjectV0, it is a frozen copy of the schema at that version.
+// The validation and state functions are left out since they depend on the
+// helpers of the current schema, only the shape of the schema is used to
+// decode the old states.
+func resourceProjectV1() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"project_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"source_url": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_file", "source_dir"},
+			},
+			"source_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_url", "source_dir"},
+			},
+			"source_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"source_dir": {
+				Type:          schema.TypeList,
+				MaxItems:      1,
+				Optional:      true,
+				ConflictsWith: []string{"github_link", "source_url", "source_file"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"entrypoint": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"import_map": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"include": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"exclude": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"source_dir_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"github_link": {
+				Type:          schema.TypeList,
+				MaxItems:      1,
+				Optional:      true,
+				ConflictsWith: []string{"source_url", "source_file", "source_dir"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"organization": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"repo": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"entrypoint": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"production_branch": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Computed: true,
+						},
+						"preview_deployments": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"mode": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "automatic",
+							ValidateFunc: validation.StringInSlice([]string{"automatic", "github_actions"}, false),
+						},
+						"install_command": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"build_command": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"repository_id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"health_check": {
+				Type:     schema.TypeList,
+				MaxItems: 1,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "/",
+						},
+						"expected_status": {
+							Type:     schema.TypeInt,
+							Optional: true,
+							Default:  200,
+						},
+						"expected_body": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"production_deployment": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"url": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"domain_mappings": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeMap,
+								Elem: &schema.Schema{Type: schema.TypeString},
+							},
+						},
+						"related_commit": {
+							Type:     schema.TypeList,
+							MaxItems: 1,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"hash": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"message": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_name": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_email": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"author_github_username": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+									"url": {
+										Type:     schema.TypeString,
+										Required: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+								},
+							},
+						},
+						"env_var": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"updated_at": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"has_production_deployment": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"env_var": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:     schema.TypeString,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"value": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+							Elem:      &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"env_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"env_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"env_file_keys": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"secret_env_var": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"value": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// resourceProjectStateUpgradeV1 converts the `domain_mappings` of the
+// production deployment from maps to blocks, and renames its `env_var`
+// attribute to `env_var_keys`.
+func resourceProjectStateUpgradeV1(_ context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	if rawState == nil {
+		return rawState, nil
+	}
+
+	l, ok := rawState["production_deployment"].([]interface{})
+	if !ok {
+		return rawState, nil
+	}
+
+	for _, v := range l {
+		depl, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if mappings, ok := depl["domain_mappings"].([]interface{}); ok {
+			domains := []interface{}{}
+			for _, m := range mappings {
+				mapping, ok := m.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				domains = append(domains, map[string]interface{}{
+					"domain":     mapping["domain"],
+					"updated_at": mapping["updated_at"],
+					"created_at": mapping["created_at"],
+				})
+			}
+			depl["domain_mappings"] = domains
+		}
+
+		if keys, ok := depl["env_var"]; ok {
+			depl["env_var_keys"] = keys
+			delete(depl, "env_var")
+		}
+	}
+
+	return rawState, nil
+}
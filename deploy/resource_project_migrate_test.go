@@ -0,0 +1,208 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResourceProjectStateUpgradeV0(t *testing.T) {
+	cases := []struct {
+		name     string
+		rawState map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no env vars",
+			rawState: map[string]interface{}{"name": "test"},
+			expected: map[string]interface{}{"name": "test"},
+		},
+		{
+			name: "unique keys",
+			rawState: map[string]interface{}{
+				"env_var": []interface{}{
+					map[string]interface{}{"key": "foo", "value": "bar"},
+					map[string]interface{}{"key": "fruit", "value": "banana"},
+				},
+			},
+			expected: map[string]interface{}{
+				"env_var": []interface{}{
+					map[string]interface{}{"key": "foo", "value": "bar"},
+					map[string]interface{}{"key": "fruit", "value": "banana"},
+				},
+			},
+		},
+		{
+			name: "duplicate keys",
+			rawState: map[string]interface{}{
+				"env_var": []interface{}{
+					map[string]interface{}{"key": "foo", "value": "bar"},
+					map[string]interface{}{"key": "fruit", "value": "banana"},
+					map[string]interface{}{"key": "foo", "value": "baz"},
+				},
+			},
+			expected: map[string]interface{}{
+				"env_var": []interface{}{
+					map[string]interface{}{"key": "foo", "value": "baz"},
+					map[string]interface{}{"key": "fruit", "value": "banana"},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual, err := resourceProjectStateUpgradeV0(context.Background(), c.rawState, nil)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+// The schema of the old states must not follow the changes of the current
+// schema.
+func TestResourceProjectV0Schema(t *testing.T) {
+	ty := resourceProjectV0().CoreConfigSchema().ImpliedType()
+
+	expected := []string{
+		"env_var", "github_link", "has_production_deployment", "health_check", "id", "name",
+		"production_deployment", "project_id", "source_dir", "source_dir_hash", "source_file",
+		"source_file_hash", "source_url",
+	}
+	actual := []string{}
+	for k := range ty.AttributeTypes() {
+		actual = append(actual, k)
+	}
+	sort.Strings(actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected attributes %v, got %v", expected, actual)
+	}
+
+	if !ty.AttributeType("env_var").IsListType() {
+		t.Errorf("expected env_var to be a list, got %s", ty.AttributeType("env_var").FriendlyName())
+	}
+	mappings := ty.AttributeType("production_deployment").ElementType().AttributeType("domain_mappings")
+	if !mappings.IsListType() || !mappings.ElementType().IsMapType() {
+		t.Errorf("expected domain_mappings to be a list of maps, got %s", mappings.FriendlyName())
+	}
+}
+
+func TestResourceProjectStateUpgradeV1(t *testing.T) {
+	cases := []struct {
+		name     string
+		rawState map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no production deployment",
+			rawState: map[string]interface{}{"name": "test"},
+			expected: map[string]interface{}{"name": "test"},
+		},
+		{
+			name: "production deployment",
+			rawState: map[string]interface{}{
+				"name": "test",
+				"production_deployment": []interface{}{
+					map[string]interface{}{
+						"id":  "abcdefghijkl",
+						"url": "https://test-abcdefghijkl.deno.dev",
+						"domain_mappings": []interface{}{
+							map[string]interface{}{
+								"domain":     "test.deno.dev",
+								"updated_at": "2021-08-01T00:00:00Z",
+								"created_at": "2021-07-01T00:00:00Z",
+							},
+						},
+						"env_var":    []interface{}{"DENO_DEPLOYMENT_ID", "foo"},
+						"updated_at": "2021-08-01T00:00:00Z",
+						"created_at": "2021-07-01T00:00:00Z",
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"name": "test",
+				"production_deployment": []interface{}{
+					map[string]interface{}{
+						"id":  "abcdefghijkl",
+						"url": "https://test-abcdefghijkl.deno.dev",
+						"domain_mappings": []interface{}{
+							map[string]interface{}{
+								"domain":     "test.deno.dev",
+								"updated_at": "2021-08-01T00:00:00Z",
+								"created_at": "2021-07-01T00:00:00Z",
+							},
+						},
+						"env_var_keys": []interface{}{"DENO_DEPLOYMENT_ID", "foo"},
+						"updated_at":   "2021-08-01T00:00:00Z",
+						"created_at":   "2021-07-01T00:00:00Z",
+					},
+				},
+			},
+		},
+		{
+			name: "incomplete domain mapping",
+			rawState: map[string]interface{}{
+				"production_deployment": []interface{}{
+					map[string]interface{}{
+						"domain_mappings": []interface{}{
+							map[string]interface{}{"domain": "test.deno.dev", "extra": "ignored"},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"production_deployment": []interface{}{
+					map[string]interface{}{
+						"domain_mappings": []interface{}{
+							map[string]interface{}{"domain": "test.deno.dev", "updated_at": nil, "created_at": nil},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		actual, err := resourceProjectStateUpgradeV1(context.Background(), c.rawState, nil)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+// The schema of the old states must not follow the changes of the current
+// schema, and the upgraded states must match the current schema.
+func TestResourceProjectV1Schema(t *testing.T) {
+	ty := resourceProjectV1().CoreConfigSchema().ImpliedType()
+
+	mappings := ty.AttributeType("production_deployment").ElementType().AttributeType("domain_mappings")
+	if !mappings.IsListType() || !mappings.ElementType().IsMapType() {
+		t.Errorf("expected domain_mappings to be a list of maps, got %s", mappings.FriendlyName())
+	}
+	if !ty.AttributeType("production_deployment").ElementType().HasAttribute("env_var") {
+		t.Errorf("expected production_deployment to have an env_var attribute")
+	}
+
+	current := resourceProject().CoreConfigSchema().ImpliedType().AttributeType("production_deployment").ElementType()
+	expected := []string{"created_at", "domain", "updated_at"}
+	actual := []string{}
+	for k := range current.AttributeType("domain_mappings").ElementType().AttributeTypes() {
+		actual = append(actual, k)
+	}
+	sort.Strings(actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected domain_mappings attributes %v, got %v", expected, actual)
+	}
+	if !current.HasAttribute("env_var_keys") || current.HasAttribute("env_var") {
+		t.Errorf("expected production_deployment to have env_var_keys instead of env_var")
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+// projectSourceMode describes where the source code of a project comes from.
+type projectSourceMode int
+
+const (
+	projectSourceNone projectSourceMode = iota
+	projectSourceURL
+	projectSourceFile
+	projectSourceDir
+	projectSourceGitHub
+)
+
+// projectSourceModeOf returns the source mode of a project given the values of
+// its `source_url`, `source_file`, `source_dir` and `github_link` attributes.
+func projectSourceModeOf(sourceURL, sourceFile, sourceDir, ghLink interface{}) projectSourceMode {
+	if l, ok := ghLink.([]interface{}); ok && len(l) > 0 {
+		return projectSourceGitHub
+	}
+	if l, ok := sourceDir.([]interface{}); ok && len(l) > 0 {
+		return projectSourceDir
+	}
+	if u, ok := sourceURL.(string); ok && u != "" {
+		return projectSourceURL
+	}
+	if f, ok := sourceFile.(string); ok && f != "" {
+		return projectSourceFile
+	}
+	return projectSourceNone
+}
+
+// resourceChangeGetter is implemented by both schema.ResourceData and
+// schema.ResourceDiff, so the same logic can be used while planning and
+// applying.
+type resourceChangeGetter interface {
+	Get(string) interface{}
+	GetChange(string) (interface{}, interface{})
+	HasChange(string) bool
+}
+
+// projectSourceModeChange returns the source mode of the project before and
+// after the change.
+func projectSourceModeChange(d resourceChangeGetter) (projectSourceMode, projectSourceMode) {
+	oldURL, newURL := d.GetChange("source_url")
+	oldFile, newFile := d.GetChange("source_file")
+	oldDir, newDir := d.GetChange("source_dir")
+	oldLink, newLink := d.GetChange("github_link")
+	return projectSourceModeOf(oldURL, oldFile, oldDir, oldLink), projectSourceModeOf(newURL, newFile, newDir, newLink)
+}
+
+// projectSourceModeOfData returns the current source mode of the project.
+func projectSourceModeOfData(d *schema.ResourceData) projectSourceMode {
+	return projectSourceModeOf(d.Get("source_url"), d.Get("source_file"), d.Get("source_dir"), d.Get("github_link"))
+}
+
+// deployProjectSource creates a new production deployment from the project's
+// source URL, source file or source directory, or links it to its GitHub
+// repository, depending on the given source mode.
+//
+// The changes to the environment variables in envVars, if any, are sent along
+// with the new deployment. Linking a repository doesn't take environment
+// variables, they must be patched separately.
+//
+// New deployments are awaited until they are ready, and their health is
+// checked if the project has a `health_check` block.
+func deployProjectSource(d *schema.ResourceData, c *client.Client, mode projectSourceMode, envVars client.EnvVarsPatch, timeout time.Duration) error {
+	var depl client.Deployment
+	var err error
+	switch mode {
+	case projectSourceURL:
+		depl, err = c.NewProjectDeployment(d.Id(), client.NewDeploymentRequest{
+			URL:        d.Get("source_url").(string),
+			Production: true,
+			EnvVars:    envVars,
+		})
+	case projectSourceFile:
+		depl, err = deploySourceFile(c, d.Id(), d.Get("source_file").(string), d.Get("source_file_hash").(string), envVars)
+	case projectSourceDir:
+		sourceDir := d.Get("source_dir").([]interface{})[0].(map[string]interface{})
+		depl, err = deploySourceDir(c, d.Id(), sourceDir, d.Get("source_dir_hash").(string), envVars)
+	case projectSourceGitHub:
+		// deployments of linked projects are created asynchronously by Deploy
+		ghLink := d.Get("github_link").([]interface{})[0].(map[string]interface{})
+		_, err = c.LinkProject(expandGitHubLink(d.Id(), ghLink))
+		return err
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if depl, err = waitForDeployment(c, d.Id(), depl.ID, timeout); err != nil {
+		return err
+	}
+
+	if hc, ok := d.GetOk("health_check"); ok {
+		healthCheck := hc.([]interface{})[0].(map[string]interface{})
+		remaining := timeout - time.Since(start)
+		if remaining < 0 {
+			remaining = 0
+		}
+		return waitForDeploymentHealthy(depl, healthCheck, remaining)
+	}
+	return nil
+}
+
+// waitForDeployment polls a deployment until it is either ready or failed.
+func waitForDeployment(c *client.Client, projectID, deploymentID string, timeout time.Duration) (client.Deployment, error) {
+	conf := &resource.StateChangeConf{
+		Pending: []string{client.DeploymentStatusPending},
+		Target:  []string{client.DeploymentStatusSuccess},
+		Refresh: func() (interface{}, string, error) {
+			depl, err := c.GetDeployment(projectID, deploymentID)
+			if err != nil {
+				return nil, "", err
+			}
+			switch depl.Status {
+			case client.DeploymentStatusFailed:
+				return depl, depl.Status, fmt.Errorf("deployment %s failed: %s", depl.ID, depl.Error)
+			case "":
+				// deployments created before the status was introduced are
+				// always ready
+				return depl, client.DeploymentStatusSuccess, nil
+			default:
+				return depl, depl.Status, nil
+			}
+		},
+		Timeout:    timeout,
+		Delay:      time.Second,
+		MinTimeout: time.Second,
+	}
+
+	result, err := conf.WaitForStateContext(context.Background())
+	if err != nil {
+		return client.Deployment{}, fmt.Errorf("error waiting for deployment %s to be ready: %w", deploymentID, err)
+	}
+	return result.(client.Deployment), nil
+}
+
+// healthCheckProbeTimeout is how long a single health check request can take.
+const healthCheckProbeTimeout = 10 * time.Second
+
+// healthCheckHTTPClient sends the health check requests. It is separate from
+// the client of the API: the requests go to the user's deployment, they must
+// not be traced or rate limited along with the API requests, and a deployment
+// that hangs must not block past the timeout of the resource.
+var healthCheckHTTPClient = &http.Client{Timeout: healthCheckProbeTimeout}
+
+// waitForDeploymentHealthy issues HTTP GET requests against the deployment
+// until it responds with the expected status and body, or the timeout expires.
+func waitForDeploymentHealthy(depl client.Deployment, healthCheck map[string]interface{}, timeout time.Duration) error {
+	if len(depl.DomainMappings) == 0 {
+		return fmt.Errorf("cannot check the health of deployment %s: it has no domain", depl.ID)
+	}
+
+	url := fmt.Sprintf("https://%s%s", depl.DomainMappings[0].Domain, healthCheck["path"].(string))
+	expectedStatus := healthCheck["expected_status"].(int)
+	expectedBody := healthCheck["expected_body"].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := resource.RetryContext(ctx, timeout, func() *resource.RetryError {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		resp, err := healthCheckHTTPClient.Do(req)
+		if err != nil {
+			return resource.RetryableError(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return resource.RetryableError(err)
+		}
+
+		if resp.StatusCode != expectedStatus {
+			return resource.RetryableError(fmt.Errorf("expected status %d, got %d (%s)", expectedStatus, resp.StatusCode, http.StatusText(resp.StatusCode)))
+		}
+		if !strings.Contains(string(body), expectedBody) {
+			return resource.RetryableError(errors.New("response body does not contain the expected body"))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("health check of deployment %s failed on %s: %w", depl.ID, url, err)
+	}
+	return nil
+}
+
+// updateProjectSource applies changes to the source of the project, including
+// transitions between a source URL and a GitHub link.
+//
+// The steps of a transition are ordered so that the final production
+// deployment always comes from the new source:
+//
+//   - leaving GitHub: the repository is unlinked first, then the new source URL
+//     (if any) is deployed. Deploying first would let a push to the repository
+//     replace the new deployment before the link is removed.
+//   - entering GitHub: the repository is linked, which creates a new deployment
+//     from the repository, then the source URL is dropped from the state.
+//   - staying on the same source: the source URL is redeployed, the source file
+//     or directory is uploaded again or the repository is re-linked when their
+//     configuration changed. Local sources are only redeployed when their
+//     content changed, not when they are moved.
+//
+// The changes to the environment variables in envVars are sent along with the
+// new deployment, so that the update results in a single deployment with both
+// the new source and the new variables. Otherwise they are patched after the
+// source changed, which redeploys the production deployment with them: when
+// the source doesn't change, or when a repository is linked, since linking
+// doesn't take environment variables.
+func updateProjectSource(d *schema.ResourceData, c *client.Client, envVars client.EnvVarsPatch) error {
+	from, to, changed := projectSourceChange(d)
+
+	if from == projectSourceGitHub && to != projectSourceGitHub {
+		if err := c.Unlink(d.Id()); err != nil {
+			return err
+		}
+	}
+
+	if changed {
+		var deployEnvVars client.EnvVarsPatch
+		if to != projectSourceGitHub {
+			deployEnvVars, envVars = envVars, nil
+		}
+		if err := deployProjectSource(d, c, to, deployEnvVars, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+	}
+
+	if len(envVars) > 0 {
+		if err := c.PatchEnvVars(d.Id(), envVars); err != nil {
+			return err
+		}
+	}
+
+	if to == projectSourceGitHub {
+		return d.Set("source_url", "")
+	}
+	// Removing the source doesn't remove the current production deployment,
+	// there's nothing to do on the Deploy side.
+	return nil
+}
+
+// projectSourceChange returns the source mode of the project before and after
+// the change, and whether the new source must be deployed, or linked in the
+// case of a GitHub repository. Local sources are only deployed again when
+// their content changed.
+func projectSourceChange(d resourceChangeGetter) (projectSourceMode, projectSourceMode, bool) {
+	from, to := projectSourceModeChange(d)
+	switch to {
+	case projectSourceURL:
+		return from, to, from != to || d.HasChange("source_url")
+	case projectSourceFile:
+		return from, to, from != to || d.HasChange("source_file_hash")
+	case projectSourceDir:
+		return from, to, from != to || d.HasChange("source_dir_hash")
+	case projectSourceGitHub:
+		return from, to, from != to || d.HasChange("github_link")
+	default:
+		return from, to, false
+	}
+}
+
+// projectSourceDeploys reports whether the change of the project's source
+// creates a new production deployment. Linking a repository in
+// `github_actions` mode doesn't, the deployments are uploaded by the
+// repository's workflow.
+func projectSourceDeploys(d resourceChangeGetter) bool {
+	_, to, changed := projectSourceChange(d)
+	if to == projectSourceGitHub {
+		return changed && d.Get("github_link.0.mode").(string) != client.GitHubModeGitHubActions
+	}
+	return changed
+}
+
+// customizeDiffSourceFile computes the hash of the project's source file.
+func customizeDiffSourceFile(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source_file") {
+		return d.SetNewComputed("source_file_hash")
+	}
+
+	hash := ""
+	if path, ok := d.GetOk("source_file"); ok {
+		var err error
+		if hash, err = sourceFileHash(path.(string)); err != nil {
+			return err
+		}
+	}
+
+	if hash != d.Get("source_file_hash").(string) {
+		return d.SetNew("source_file_hash", hash)
+	}
+	return nil
+}
+
+// sourceFileHash returns the hex encoded SHA-256 hash of the file's content.
+func sourceFileHash(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading source file: %w", err)
+	}
+	return contentHash(content), nil
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// deploySourceFile uploads a local file and creates a new production
+// deployment using it as the entrypoint, along with the changes to the
+// environment variables in envVars. The expectedHash is the hash of the file
+// computed during the plan, the deployment fails if the file changed since
+// then.
+func deploySourceFile(c *client.Client, projectID, path, expectedHash string, envVars client.EnvVarsPatch) (client.Deployment, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return client.Deployment{}, fmt.Errorf("error reading source file: %w", err)
+	}
+
+	if hash := contentHash(content); expectedHash != "" && hash != expectedHash {
+		return client.Deployment{}, fmt.Errorf("source file %s changed since the plan was created", path)
+	}
+
+	name := filepath.Base(path)
+	entry := client.NewFileEntry(content)
+	return c.NewProjectDeploymentWithAssets(projectID, client.NewDeploymentWithAssetsRequest{
+		URL:        client.AssetsRootURL + name,
+		Production: true,
+		Manifest: client.Manifest{
+			Entries: map[string]client.ManifestEntry{name: entry},
+		},
+		EnvVars: envVars,
+	}, map[string][]byte{entry.GitSHA1: content})
+}
+
+// customizeDiffSourceDir computes the hash of the project's source directory,
+// that is the hash of the deployment request built from its manifest.
+func customizeDiffSourceDir(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source_dir") {
+		return d.SetNewComputed("source_dir_hash")
+	}
+
+	hash := ""
+	if v, ok := d.GetOk("source_dir"); ok {
+		sourceDir := v.([]interface{})[0].(map[string]interface{})
+		req, _, err := expandSourceDir(sourceDir)
+		if err != nil {
+			return err
+		}
+		if hash, err = sourceDirHash(req); err != nil {
+			return err
+		}
+	}
+
+	if hash != d.Get("source_dir_hash").(string) {
+		return d.SetNew("source_dir_hash", hash)
+	}
+	return nil
+}
+
+// expandSourceDir builds the manifest of a `source_dir` block and returns the
+// request used to deploy it, along with the local path of the files in the
+// manifest keyed by their hash.
+func expandSourceDir(tfMap map[string]interface{}) (client.NewDeploymentWithAssetsRequest, map[string]string, error) {
+	manifest, files, err := client.BuildManifest(client.ManifestOptions{
+		Root:    tfMap["path"].(string),
+		Include: expandStringList(tfMap["include"].([]interface{})),
+		Exclude: expandStringList(tfMap["exclude"].([]interface{})),
+	})
+	if err != nil {
+		return client.NewDeploymentWithAssetsRequest{}, nil, fmt.Errorf("error reading source directory: %w", err)
+	}
+
+	entrypoint := path.Clean(tfMap["entrypoint"].(string))
+	if _, ok := manifest.Lookup(entrypoint); !ok {
+		return client.NewDeploymentWithAssetsRequest{}, nil, fmt.Errorf("entrypoint %s is not part of the source directory", entrypoint)
+	}
+
+	req := client.NewDeploymentWithAssetsRequest{
+		URL:        client.AssetsRootURL + entrypoint,
+		Production: true,
+		Manifest:   manifest,
+	}
+
+	if importMap := tfMap["import_map"].(string); importMap != "" {
+		importMap = path.Clean(importMap)
+		if _, ok := manifest.Lookup(importMap); !ok {
+			return client.NewDeploymentWithAssetsRequest{}, nil, fmt.Errorf("import map %s is not part of the source directory", importMap)
+		}
+		req.ImportMapURL = client.AssetsRootURL + importMap
+	}
+
+	return req, files, nil
+}
+
+// sourceDirHash returns the hex encoded SHA-256 hash of the deployment request
+// of a source directory. Since the manifest contains the hash of every file,
+// it changes whenever a file is added, removed or modified.
+func sourceDirHash(req client.NewDeploymentWithAssetsRequest) (string, error) {
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	return contentHash(bs), nil
+}
+
+// deploySourceDir uploads the files of a local directory that Deploy doesn't
+// already have and creates a new production deployment from it, along with
+// the changes to the environment variables in envVars. The expectedHash is the
+// hash of the directory computed during the plan, the deployment fails if the
+// directory changed since then.
+func deploySourceDir(c *client.Client, projectID string, tfMap map[string]interface{}, expectedHash string, envVars client.EnvVarsPatch) (client.Deployment, error) {
+	req, files, err := expandSourceDir(tfMap)
+	if err != nil {
+		return client.Deployment{}, err
+	}
+
+	hash, err := sourceDirHash(req)
+	if err != nil {
+		return client.Deployment{}, err
+	}
+	if expectedHash != "" && hash != expectedHash {
+		return client.Deployment{}, fmt.Errorf("source directory %s changed since the plan was created", tfMap["path"])
+	}
+
+	// the environment variables are not part of the hash of the directory
+	req.EnvVars = envVars
+	return c.NewProjectDeploymentFromManifest(projectID, req, files)
+}
+
+func expandStringList(l []interface{}) []string {
+	result := make([]string, 0, len(l))
+	for _, v := range l {
+		result = append(result, v.(string))
+	}
+	return result
+}
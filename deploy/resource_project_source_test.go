@@ -0,0 +1,137 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wperron/terraform-deploy-provider/client"
+)
+
+// testServerTransport sends every request to the test server, whatever their
+// host.
+type testServerTransport struct {
+	target *url.URL
+}
+
+func (t testServerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWaitForDeployment(t *testing.T) {
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/projects/123/deployments/ready":
+			status := client.DeploymentStatusPending
+			if atomic.AddInt32(&polls, 1) > 1 {
+				status = client.DeploymentStatusSuccess
+			}
+			fmt.Fprintf(w, `{"id":"ready","status":%q}`, status)
+		case "/api/projects/123/deployments/broken":
+			fmt.Fprint(w, `{"id":"broken","status":"failed","error":"Uncaught SyntaxError: Unexpected token"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	c := client.New("token")
+	c.Logger = nil
+	c.HTTPClient = &http.Client{Transport: testServerTransport{target: target}}
+
+	depl, err := waitForDeployment(c, "123", "ready", 10*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if depl.ID != "ready" || depl.Status != client.DeploymentStatusSuccess {
+		t.Errorf("expected the ready deployment, got %+v", depl)
+	}
+	if n := atomic.LoadInt32(&polls); n != 2 {
+		t.Errorf("expected the deployment to be polled until ready, got %d polls", n)
+	}
+
+	_, err = waitForDeployment(c, "123", "broken", 10*time.Second)
+	if err == nil {
+		t.Fatal("expected an error for a failed deployment")
+	}
+	if !strings.Contains(err.Error(), "Uncaught SyntaxError: Unexpected token") {
+		t.Errorf("expected the error of the deployment, got %q", err)
+	}
+}
+
+func TestWaitForDeploymentHealthy(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthy":
+			fmt.Fprint(w, "Hello World!")
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "Not the page you are looking for")
+		}
+	}))
+	defer srv.Close()
+
+	// the certificate of the test server is valid for example.com, the
+	// requests to the deployment's domain are sent to the test server
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	healthCheckClient := healthCheckHTTPClient
+	healthCheckHTTPClient = &http.Client{Timeout: healthCheckProbeTimeout, Transport: transport}
+	defer func() { healthCheckHTTPClient = healthCheckClient }()
+
+	depl := client.Deployment{
+		ID:             "abcdefghijkl",
+		DomainMappings: []client.DomainMapping{{Domain: "example.com"}},
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+		err    string
+	}{
+		{name: "healthy", path: "/healthy", status: 200, body: "Hello"},
+		{name: "status mismatch", path: "/unavailable", status: 200, err: "expected status 200, got 503"},
+		{name: "body mismatch", path: "/other", status: 200, body: "Hello", err: "does not contain the expected body"},
+	}
+
+	for _, c := range cases {
+		healthCheck := map[string]interface{}{
+			"path":            c.path,
+			"expected_status": c.status,
+			"expected_body":   c.body,
+		}
+		start := time.Now()
+		err := waitForDeploymentHealthy(depl, healthCheck, time.Second)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.err, err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("%s: expected the health check to be retried until the timeout, failed after %s", c.name, elapsed)
+		}
+	}
+}
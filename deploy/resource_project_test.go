@@ -2,17 +2,19 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"regexp"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/wperron/terraform-deploy-provider/client"
 )
 
 func TestAccProject_basic(t *testing.T) {
-	randomID := acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	randomID := testAccRandomID(t)
 
 	config := fmt.Sprintf(testAccProjectConfig_basic, randomID)
 	updated := fmt.Sprintf(testAccProjectConfig_update, randomID)
@@ -37,6 +39,12 @@ func TestAccProject_basic(t *testing.T) {
 					resource.TestCheckResourceAttrSet(
 						"deploy_project.test", "id",
 					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "default_domain", fmt.Sprintf("terraform-test-%s.deno.dev", randomID),
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "url", fmt.Sprintf("https://terraform-test-%s.deno.dev", randomID),
+					),
 				),
 			},
 			{
@@ -66,9 +74,10 @@ func TestAccProject_basic(t *testing.T) {
 }
 
 func TestAccProject_envVars(t *testing.T) {
-	randomID := acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	randomID := testAccRandomID(t)
 
 	config := fmt.Sprintf(testAccProjectConfig_envVars, randomID)
+	updated := fmt.Sprintf(testAccProjectConfig_envVarsUpdate, randomID)
 
 	var project client.Project
 	source := "https://dash.deno.com/examples/hello.js"
@@ -99,12 +108,19 @@ func TestAccProject_envVars(t *testing.T) {
 					}),
 				),
 			},
+			{
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccEnvVarCheckKeys(&project, []string{"foo", "greeting"}, []string{"fruit"}),
+				),
+			},
 		},
 	})
 }
 
 func TestAccProject_github(t *testing.T) {
-	randomID := acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	randomID := testAccRandomID(t)
 
 	config := fmt.Sprintf(testAccProjectConfig_github, randomID)
 
@@ -127,6 +143,12 @@ func TestAccProject_github(t *testing.T) {
 					resource.TestCheckResourceAttrSet(
 						"deploy_project.test", "id",
 					),
+					resource.TestCheckResourceAttrSet(
+						"deploy_project.test", "github_link.0.repository_id",
+					),
+					resource.TestCheckResourceAttrSet(
+						"deploy_project.test", "github_link.0.production_branch",
+					),
 					testAccProjectDeployment(&project, testProductionDeployment{
 						EnvVars: make(client.NewEnvVars),
 						GitHub: &testGitHub{
@@ -142,7 +164,7 @@ func TestAccProject_github(t *testing.T) {
 }
 
 func TestAccProject_linkAndUnlink(t *testing.T) {
-	randomID := acctest.RandStringFromCharSet(4, acctest.CharSetAlphaNum)
+	randomID := testAccRandomID(t)
 
 	config := fmt.Sprintf(testAccProjectConfig_update, randomID)
 	linked := fmt.Sprintf(testAccProjectConfig_github, randomID)
@@ -220,6 +242,509 @@ func TestAccProject_linkAndUnlink(t *testing.T) {
 	})
 }
 
+func TestAccProject_secretEnvVars(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_secretEnvVars, randomID, "hunter2")
+	updated := fmt.Sprintf(testAccProjectConfig_secretEnvVars, randomID, "hunter3")
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckTypeSetElemNestedAttrs("deploy_project.test", "secret_env_var.*", map[string]string{
+						"key":   "API_KEY",
+						"value": "",
+					}),
+					testAccCheckSecretValue("deploy_project.test", "secret_env_var_hashes.API_KEY", "hunter2"),
+					testAccEnvVarCheckKeys(&project, []string{"foo", "API_KEY"}, nil),
+				),
+			},
+			{
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckTypeSetElemNestedAttrs("deploy_project.test", "secret_env_var.*", map[string]string{
+						"key":   "API_KEY",
+						"value": "",
+					}),
+					testAccCheckSecretValue("deploy_project.test", "secret_env_var_hashes.API_KEY", "hunter3"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProject_envFile(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_envFile, randomID, "testdata/app.env")
+	updated := fmt.Sprintf(testAccProjectConfig_envFile, randomID, "testdata/app_update.env")
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttrSet(
+						"deploy_project.test", "env_file_hash",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "env_file_keys.#", "3",
+					),
+					testAccEnvVarCheckKeys(&project, []string{"FOO", "FRUIT", "GREETING"}, nil),
+				),
+			},
+			{
+				// the content of the file didn't change, nothing to update
+				Config:   config,
+				PlanOnly: true,
+			},
+			{
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "env_file_keys.#", "2",
+					),
+					testAccEnvVarCheckKeys(&project, []string{"FOO", "GREETING"}, []string{"FRUIT"}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProject_invalidEnvFile(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testAccProjectConfig_envFile, randomID, "testdata/invalid.env"),
+				ExpectError: regexp.MustCompile(`line 2: unterminated double quoted value`),
+			},
+		},
+	})
+}
+
+func TestAccProject_invalidEnvVarKey(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testAccProjectConfig_invalidEnvVarKey, randomID),
+				ExpectError: regexp.MustCompile(`must not start with DENO_`),
+			},
+		},
+	})
+}
+
+func TestAccProject_singleDeployment(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_singleDeployment, randomID, "https://dash.deno.com/examples/hello.js", "bar")
+	updated := fmt.Sprintf(testAccProjectConfig_singleDeployment, randomID, "https://deno.land/std@0.100.0/examples/welcome.ts", "baz")
+
+	var project client.Project
+	var count int
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectDeploymentCount(&project, &count),
+				),
+			},
+			{
+				// changing both the source and the environment variables
+				// creates a single deployment
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectCheckDeploymentCount(&project, &count, 1),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProject_githubEnvVarsDeployments(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_githubEnvVars, randomID, "/deploy/testdata/main.ts", "bar")
+	updated := fmt.Sprintf(testAccProjectConfig_githubEnvVars, randomID, "/deploy/testdata/source_dir/main.ts", "baz")
+
+	var project client.Project
+	var count int
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectDeploymentCount(&project, &count),
+				),
+			},
+			{
+				// linking a repository doesn't take environment variables:
+				// changing both the link and the variables creates one
+				// deployment when linking and another one when updating the
+				// variables
+				Config: updated,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectCheckDeploymentCount(&project, &count, 2),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProject_duplicateEnvVars(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testAccProjectConfig_duplicateEnvVars, randomID),
+				ExpectError: regexp.MustCompile(`duplicate environment variable "foo"`),
+			},
+		},
+	})
+}
+
+func TestAccProject_githubToSourceURL(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	linked := fmt.Sprintf(testAccProjectConfig_github, randomID)
+	config := fmt.Sprintf(testAccProjectConfig_update, randomID)
+
+	var project client.Project
+	source := "https://dash.deno.com/examples/hello.js"
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: linked,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_url", "",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "github_link.#", "1",
+					),
+				),
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectCheckNotLinked(&project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_url", source,
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "github_link.#", "0",
+					),
+					testAccProjectDeployment(&project, testProductionDeployment{
+						SourceUrl: &source,
+						EnvVars:   make(client.NewEnvVars),
+					}),
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccProject_removeSource(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	basic := fmt.Sprintf(testAccProjectConfig_basic, randomID)
+	config := fmt.Sprintf(testAccProjectConfig_update, randomID)
+	linked := fmt.Sprintf(testAccProjectConfig_github, randomID)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "has_production_deployment", "true",
+					),
+				),
+			},
+			{
+				// source URL to no source
+				Config: basic,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_url", "",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "has_production_deployment", "true",
+					),
+				),
+			},
+			{
+				// no source to GitHub
+				Config: linked,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "github_link.#", "1",
+					),
+				),
+			},
+			{
+				// GitHub to no source
+				Config: basic,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					testAccProjectCheckNotLinked(&project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "github_link.#", "0",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_url", "",
+					),
+				),
+			},
+			{
+				Config:   basic,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccProject_sourceFile(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_sourceFile, randomID)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "has_production_deployment", "true",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_file", "testdata/main.ts",
+					),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "source_file_hash", "534b2798a392efb41b7c6fa6c2c45298c2e216be0743849b0cd351c986d1d865",
+					),
+					testAccProjectDeployment(&project, testProductionDeployment{
+						EnvVars: make(client.NewEnvVars),
+					}),
+				),
+			},
+			{
+				// the content of the file didn't change, no new deployment
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccProject_sourceDir(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_sourceDir, randomID)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "has_production_deployment", "true",
+					),
+					resource.TestCheckResourceAttrSet(
+						"deploy_project.test", "source_dir_hash",
+					),
+					testAccProjectDeployment(&project, testProductionDeployment{
+						EnvVars: make(client.NewEnvVars),
+					}),
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccProject_healthCheck(t *testing.T) {
+	randomID := testAccRandomID(t)
+
+	config := fmt.Sprintf(testAccProjectConfig_healthCheck, randomID)
+
+	var project client.Project
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccProjectCheckDestroy(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testAccProjectCheckExists("deploy_project.test", &project),
+					resource.TestCheckResourceAttr(
+						"deploy_project.test", "has_production_deployment", "true",
+					),
+					testAccProjectCheckDeploymentStatus(&project, client.DeploymentStatusSuccess),
+				),
+			},
+		},
+	})
+}
+
+func testAccProjectCheckDeploymentStatus(p *client.Project, status string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if p.ProductionDeployment == nil {
+			return fmt.Errorf("no production deployment found")
+		}
+		client := testAccProvider.Meta().(*client.Client)
+		depl, err := client.GetDeployment(p.ID, p.ProductionDeployment.ID)
+		if err != nil {
+			return fmt.Errorf("error getting deployment: %s", err)
+		}
+		if depl.Status != status {
+			return fmt.Errorf("expected deployment status %q, got %q", status, depl.Status)
+		}
+		return nil
+	}
+}
+
+func testAccProjectCheckNotLinked(p *client.Project) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if p.Git != nil {
+			return fmt.Errorf("expected project to be unlinked, found %s/%s", p.Git.Repository.Owner, p.Git.Repository.Name)
+		}
+		return nil
+	}
+}
+
+func TestDiffEnvVars(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	cases := []struct {
+		old      client.NewEnvVars
+		new      client.NewEnvVars
+		expected client.EnvVarsPatch
+	}{
+		{
+			old:      client.NewEnvVars{"foo": "bar"},
+			new:      client.NewEnvVars{"foo": "bar"},
+			expected: client.EnvVarsPatch{},
+		},
+		{
+			old:      client.NewEnvVars{},
+			new:      client.NewEnvVars{"foo": "bar"},
+			expected: client.EnvVarsPatch{"foo": str("bar")},
+		},
+		{
+			old:      client.NewEnvVars{"foo": "bar", "fruit": "apple"},
+			new:      client.NewEnvVars{"foo": "bar", "fruit": "banana"},
+			expected: client.EnvVarsPatch{"fruit": str("banana")},
+		},
+		{
+			old:      client.NewEnvVars{"foo": "bar", "fruit": "apple"},
+			new:      client.NewEnvVars{"fruit": "apple", "greeting": "hello"},
+			expected: client.EnvVarsPatch{"foo": nil, "greeting": str("hello")},
+		},
+	}
+
+	for _, c := range cases {
+		if actual := diffEnvVars(c.old, c.new); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("diffEnvVars(%v, %v): expected %v, got %v", c.old, c.new, c.expected, actual)
+		}
+	}
+}
+
+// testAccProjectDeploymentCount stores the number of deployments of the
+// project in count.
+func testAccProjectDeploymentCount(p *client.Project, count *int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		c := testAccProvider.Meta().(*client.Client)
+		_, paging, err := c.ListDeployments(p.ID, client.PageOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		*count = paging.TotalCount
+		return nil
+	}
+}
+
+// testAccProjectCheckDeploymentCount checks that the project has exactly
+// `created` more deployments than count.
+func testAccProjectCheckDeploymentCount(p *client.Project, count *int, created int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		c := testAccProvider.Meta().(*client.Client)
+		_, paging, err := c.ListDeployments(p.ID, client.PageOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if paging.TotalCount != *count+created {
+			return fmt.Errorf("expected %d new deployments, got %d", created, paging.TotalCount-*count)
+		}
+		return nil
+	}
+}
+
 func testAccProjectCheckExists(rn string, p *client.Project) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[rn]
@@ -329,6 +854,140 @@ resource "deploy_project" "test" {
 }
 `
 
+const testAccProjectConfig_envVarsUpdate = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+
+  env_var {
+    key   = "foo"
+    value = "baz"
+  }
+
+  env_var {
+    key   = "greeting"
+    value = "Hello World!"
+  }
+}
+`
+
+const testAccProjectConfig_secretEnvVars = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+
+  env_var {
+    key   = "foo"
+    value = "bar"
+  }
+
+  secret_env_var {
+    key   = "API_KEY"
+    value = "%s"
+  }
+}
+`
+
+const testAccProjectConfig_envFile = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+  env_file   = "%s"
+
+  env_var {
+    key   = "FOO"
+    value = "bar"
+  }
+}
+`
+
+const testAccProjectConfig_invalidEnvVarKey = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+
+  env_var {
+    key   = "DENO_REGION"
+    value = "gcp-us-east1"
+  }
+}
+`
+
+const testAccProjectConfig_singleDeployment = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "%s"
+
+  env_var {
+    key   = "foo"
+    value = "%s"
+  }
+}
+`
+
+const testAccProjectConfig_githubEnvVars = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+
+  github_link {
+    organization = "wperron"
+    repo         = "terraform-deploy-provider"
+    entrypoint   = "%s"
+  }
+
+  env_var {
+    key   = "foo"
+    value = "%s"
+  }
+}
+`
+
+const testAccProjectConfig_duplicateEnvVars = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+
+  env_var {
+    key   = "foo"
+    value = "bar"
+  }
+
+  env_var {
+    key   = "foo"
+    value = "baz"
+  }
+}
+`
+
+const testAccProjectConfig_sourceFile = `
+resource "deploy_project" "test" {
+  name        = "terraform-test-%s"
+  source_file = "testdata/main.ts"
+}
+`
+
+const testAccProjectConfig_sourceDir = `
+resource "deploy_project" "test" {
+  name = "terraform-test-%s"
+
+  source_dir {
+    path       = "testdata/source_dir"
+    entrypoint = "main.ts"
+    include    = ["**/*.ts"]
+  }
+}
+`
+
+const testAccProjectConfig_healthCheck = `
+resource "deploy_project" "test" {
+  name       = "terraform-test-%s"
+  source_url = "https://dash.deno.com/examples/hello.js"
+
+  health_check {
+    path            = "/"
+    expected_status = 200
+  }
+}
+`
+
 const testAccProjectConfig_github = `
 resource "deploy_project" "test" {
   name = "terraform-test-%s"
@@ -340,11 +999,135 @@ resource "deploy_project" "test" {
 }
 `
 
-func includes(l []string, s string) bool {
-	for _, e := range l {
-		if e == s {
-			return true
+func TestCustomizeDiffProductionDeployment(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "00000000-0000-0000-0000-000000000000",
+		Attributes: map[string]string{
+			"id":                                     "00000000-0000-0000-0000-000000000000",
+			"name":                                   "terraform-test",
+			"source_url":                             "https://dash.deno.com/examples/hello.js",
+			"has_production_deployment":              "true",
+			"production_deployment.#":                "1",
+			"production_deployment.0.id":             "abcdefghijkl",
+			"production_deployment.0.url":            "https://dash.deno.com/examples/hello.js",
+			"production_deployment.0.env_var_keys.#": "1",
+			"production_deployment.0.env_var_keys.0": "DENO_DEPLOYMENT_ID",
+			"default_domain":                         "terraform-test.deno.dev",
+			"url":                                    "https://terraform-test.deno.dev",
+		},
+	}
+
+	cases := []struct {
+		name     string
+		config   map[string]interface{}
+		computed bool
+	}{
+		{
+			name: "no change",
+			config: map[string]interface{}{
+				"name":       "terraform-test",
+				"source_url": "https://dash.deno.com/examples/hello.js",
+			},
+			computed: false,
+		},
+		{
+			name: "rename",
+			config: map[string]interface{}{
+				"name":       "terraform-test-renamed",
+				"source_url": "https://dash.deno.com/examples/hello.js",
+			},
+			computed: false,
+		},
+		{
+			name: "source change",
+			config: map[string]interface{}{
+				"name":       "terraform-test",
+				"source_url": "https://deno.land/std@0.100.0/examples/welcome.ts",
+			},
+			computed: true,
+		},
+		{
+			name: "env var change",
+			config: map[string]interface{}{
+				"name":       "terraform-test",
+				"source_url": "https://dash.deno.com/examples/hello.js",
+				"env_var": []interface{}{
+					map[string]interface{}{"key": "foo", "value": "bar"},
+				},
+			},
+			computed: true,
+		},
+		{
+			name: "link in github_actions mode",
+			config: map[string]interface{}{
+				"name": "terraform-test",
+				"github_link": []interface{}{
+					map[string]interface{}{
+						"organization": "denoland",
+						"repo":         "deploy_examples",
+						"entrypoint":   "/hello/main.ts",
+						"mode":         client.GitHubModeGitHubActions,
+					},
+				},
+			},
+			computed: false,
+		},
+		{
+			name: "link in automatic mode",
+			config: map[string]interface{}{
+				"name": "terraform-test",
+				"github_link": []interface{}{
+					map[string]interface{}{
+						"organization": "denoland",
+						"repo":         "deploy_examples",
+						"entrypoint":   "/hello/main.ts",
+					},
+				},
+			},
+			computed: true,
+		},
+	}
+
+	for _, c := range cases {
+		diff, err := resourceProject().Diff(context.Background(), state, terraform.NewResourceConfigRaw(c.config), nil)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+
+		computed := false
+		if diff != nil {
+			if attr, ok := diff.Attributes["production_deployment.#"]; ok {
+				computed = attr.NewComputed
+			}
+		}
+		if computed != c.computed {
+			t.Errorf("%s: expected production_deployment to be computed: %t, got %t", c.name, c.computed, computed)
+		}
+	}
+}
+
+func TestCustomizeDiffDefaultDomain(t *testing.T) {
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name": "my-test-project",
+	})
+	diff, err := resourceProject().Diff(context.Background(), nil, config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"default_domain": "my-test-project.deno.dev",
+		"url":            "https://my-test-project.deno.dev",
+	}
+	for k, v := range expected {
+		attr, ok := diff.Attributes[k]
+		if !ok {
+			t.Errorf("expected %s to be in the diff", k)
+			continue
+		}
+		if attr.NewComputed || attr.New != v {
+			t.Errorf("expected %s to be %q, got %q (computed: %t)", k, v, attr.New, attr.NewComputed)
 		}
 	}
-	return false
 }
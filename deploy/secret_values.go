@@ -0,0 +1,179 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/argon2"
+)
+
+// secretValuePrefix marks the values stored in state as a hash rather than in
+// plain text.
+const secretValuePrefix = "$argon2id$"
+
+// The parameters of the Argon2id hashes of the secret values. Argon2id is slow
+// and memory-hard by design, which makes guessing a value from its hash in
+// state expensive.
+const (
+	secretValueTime    = 2
+	secretValueMemory  = 19 * 1024
+	secretValueThreads = 1
+	secretValueKeyLen  = 32
+	secretValueSaltLen = 16
+)
+
+// hashSecretValue returns the value stored in state in place of a secret, in
+// the PHC string format:
+// `$argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>`. Every hash
+// has its own random salt, so the same value hashes differently each time; use
+// verifySecretValue to compare a value with a hash.
+//
+// A value that is already hashed is returned as is, so it can be used as a
+// StateFunc.
+func hashSecretValue(v interface{}) string {
+	s, _ := v.(string)
+	if strings.HasPrefix(s, secretValuePrefix) {
+		return s
+	}
+
+	salt := make([]byte, secretValueSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Sprintf("error generating salt: %s", err))
+	}
+	key := argon2.IDKey([]byte(s), salt, secretValueTime, secretValueMemory, secretValueThreads, secretValueKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", secretValuePrefix, argon2.Version,
+		secretValueMemory, secretValueTime, secretValueThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// verifySecretValue reports whether hash is the hash of value. The hash is
+// derived again from value with the parameters and salt stored in hash.
+func verifySecretValue(hash, value string) bool {
+	if !strings.HasPrefix(hash, secretValuePrefix) {
+		return false
+	}
+	parts := strings.Split(strings.TrimPrefix(hash, secretValuePrefix), "$")
+	if len(parts) != 4 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(value), salt, time, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// suppressSecretValueDiff suppresses the diff between the hash in state and
+// the hash of the configured value when both are hashes of the same value.
+func suppressSecretValueDiff(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return false
+	}
+	value, _ := d.Get(k).(string)
+	return verifySecretValue(old, value)
+}
+
+// validateSecretValue rejects values that would be mistaken for a hash by
+// hashSecretValue and stored as is.
+func validateSecretValue(v interface{}, k string) ([]string, []error) {
+	if strings.HasPrefix(v.(string), secretValuePrefix) {
+		return nil, []error{fmt.Errorf("%s must not start with %q", k, secretValuePrefix)}
+	}
+	return nil, nil
+}
+
+// secretEnvVarSetHash hashes the `secret_env_var` blocks on their key only:
+// their values are not kept in state, the hashes of the values are kept in
+// `secret_env_var_hashes` instead.
+func secretEnvVarSetHash(v interface{}) int {
+	m := v.(map[string]interface{})
+	return schema.HashString(m["key"].(string))
+}
+
+// blankSecretValue is the StateFunc of the values of the `secret_env_var`
+// blocks, which keeps them out of state.
+func blankSecretValue(interface{}) string {
+	return ""
+}
+
+// configSecretEnvVarKeys returns the keys of the `secret_env_var` blocks of
+// the configuration, including the keys declared more than once. The second
+// return value is false when the blocks are not known yet.
+func configSecretEnvVarKeys(config cty.Value) ([]string, bool) {
+	blocks := config.GetAttr("secret_env_var")
+	if !blocks.IsWhollyKnown() {
+		return nil, false
+	}
+	var keys []string
+	if blocks.IsNull() {
+		return keys, true
+	}
+	for it := blocks.ElementIterator(); it.Next(); {
+		_, block := it.Element()
+		if key := block.GetAttr("key"); !key.IsNull() {
+			keys = append(keys, key.AsString())
+		}
+	}
+	return keys, true
+}
+
+// configSecretEnvVars returns the values of the `secret_env_var` blocks of the
+// configuration. They are read from the raw configuration since they are not
+// kept in state or in the plan. The second return value is false when the
+// blocks are not known yet.
+func configSecretEnvVars(config cty.Value) (map[string]string, bool) {
+	blocks := config.GetAttr("secret_env_var")
+	if !blocks.IsWhollyKnown() {
+		return nil, false
+	}
+	vars := map[string]string{}
+	if blocks.IsNull() {
+		return vars, true
+	}
+	for it := blocks.ElementIterator(); it.Next(); {
+		_, block := it.Element()
+		key, value := block.GetAttr("key"), block.GetAttr("value")
+		if key.IsNull() || value.IsNull() {
+			continue
+		}
+		vars[key.AsString()] = value.AsString()
+	}
+	return vars, true
+}
+
+// hashSecretEnvVars returns the hashes of the secret values, keyed by the name
+// of their variable. The hashes in old are kept for the values that did not
+// change, so that the hashes only change along with the values.
+func hashSecretEnvVars(old map[string]interface{}, vars map[string]string) map[string]interface{} {
+	hashes := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		if hash, ok := old[k].(string); ok && verifySecretValue(hash, v) {
+			hashes[k] = hash
+		} else {
+			hashes[k] = hashSecretValue(v)
+		}
+	}
+	return hashes
+}
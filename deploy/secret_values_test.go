@@ -0,0 +1,254 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashSecretValue(t *testing.T) {
+	hashed := hashSecretValue("hunter2")
+	if !strings.HasPrefix(hashed, secretValuePrefix) {
+		t.Fatalf("expected %q to start with %q", hashed, secretValuePrefix)
+	}
+	if strings.Contains(hashed, "hunter2") {
+		t.Errorf("expected %q not to contain the plain value", hashed)
+	}
+	if again := hashSecretValue(hashed); again != hashed {
+		t.Errorf("expected hashing to be idempotent, got %q then %q", hashed, again)
+	}
+	if other := hashSecretValue("hunter2"); other == hashed {
+		t.Errorf("expected every hash to have its own salt, got %q twice", hashed)
+	}
+}
+
+func TestHashSecretValue_format(t *testing.T) {
+	parts := strings.Split(hashSecretValue("hunter2"), "$")
+	if len(parts) != 6 {
+		t.Fatalf("expected 6 parts, got %q", parts)
+	}
+	if parts[1] != "argon2id" || parts[2] != "v=19" || parts[3] != "m=19456,t=2,p=1" {
+		t.Errorf("unexpected algorithm or parameters: %q", parts[1:4])
+	}
+
+	// The hash can be verified from the parameters and salt stored with it.
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		t.Fatalf("unexpected error decoding the salt: %s", err)
+	}
+	if len(salt) != 16 {
+		t.Errorf("expected a 16 bytes salt, got %d bytes", len(salt))
+	}
+	key := argon2.IDKey([]byte("hunter2"), salt, 2, 19*1024, 1, 32)
+	if expected := base64.RawStdEncoding.EncodeToString(key); parts[5] != expected {
+		t.Errorf("expected hash %q, got %q", expected, parts[5])
+	}
+}
+
+func TestVerifySecretValue(t *testing.T) {
+	hash := hashSecretValue("hunter2")
+	parts := strings.Split(hash, "$")
+
+	cases := []struct {
+		name     string
+		hash     string
+		value    string
+		expected bool
+	}{
+		{name: "same value", hash: hash, value: "hunter2", expected: true},
+		{name: "other value", hash: hash, value: "hunter3", expected: false},
+		{name: "empty value", hash: hash, value: "", expected: false},
+		{name: "empty hash", hash: "", value: "hunter2", expected: false},
+		{name: "plain value", hash: "hunter2", value: "hunter2", expected: false},
+		{name: "other version", hash: strings.Replace(hash, "v=19", "v=16", 1), value: "hunter2", expected: false},
+		{name: "other parameters", hash: strings.Replace(hash, "t=2", "t=3", 1), value: "hunter2", expected: false},
+		{name: "invalid salt", hash: strings.Replace(hash, parts[4], "!", 1), value: "hunter2", expected: false},
+		{name: "missing hash", hash: strings.Join(parts[:5], "$"), value: "hunter2", expected: false},
+	}
+
+	for _, c := range cases {
+		if actual := verifySecretValue(c.hash, c.value); actual != c.expected {
+			t.Errorf("%s: expected %t, got %t", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestValidateSecretValue(t *testing.T) {
+	if _, errs := validateSecretValue("hunter2", "value"); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if _, errs := validateSecretValue(hashSecretValue("hunter2"), "value"); len(errs) == 0 {
+		t.Errorf("expected an error for a value starting with %q", secretValuePrefix)
+	}
+}
+
+func TestSecretEnvVarSetHash(t *testing.T) {
+	configured := map[string]interface{}{"key": "API_KEY", "value": "hunter2"}
+	stored := map[string]interface{}{"key": "API_KEY", "value": ""}
+	if secretEnvVarSetHash(configured) != secretEnvVarSetHash(stored) {
+		t.Errorf("expected the configured and stored blocks to have the same set hash")
+	}
+
+	other := map[string]interface{}{"key": "OTHER_KEY", "value": "hunter2"}
+	if secretEnvVarSetHash(configured) == secretEnvVarSetHash(other) {
+		t.Errorf("expected a different key to have a different set hash")
+	}
+}
+
+func TestHashSecretEnvVars(t *testing.T) {
+	old := map[string]interface{}{
+		"KEPT":    hashSecretValue("hunter2"),
+		"CHANGED": hashSecretValue("hunter2"),
+		"REMOVED": hashSecretValue("hunter2"),
+	}
+	hashes := hashSecretEnvVars(old, map[string]string{
+		"KEPT":    "hunter2",
+		"CHANGED": "hunter3",
+		"ADDED":   "hunter4",
+	})
+
+	if len(hashes) != 3 {
+		t.Fatalf("expected 3 hashes, got %v", hashes)
+	}
+	if hashes["KEPT"] != old["KEPT"] {
+		t.Errorf("expected the hash of an unchanged value to be kept")
+	}
+	if hashes["CHANGED"] == old["CHANGED"] || !verifySecretValue(hashes["CHANGED"].(string), "hunter3") {
+		t.Errorf("expected the changed value to be hashed again, got %q", hashes["CHANGED"])
+	}
+	if !verifySecretValue(hashes["ADDED"].(string), "hunter4") {
+		t.Errorf("expected the added value to be hashed, got %q", hashes["ADDED"])
+	}
+}
+
+func TestSuppressSecretValueDiff(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "00000000-0000-0000-0000-000000000000/API_KEY",
+		Attributes: map[string]string{
+			"id":           "00000000-0000-0000-0000-000000000000/API_KEY",
+			"project_id":   "00000000-0000-0000-0000-000000000000",
+			"key":          "API_KEY",
+			"secret_value": hashSecretValue("hunter2"),
+		},
+	}
+
+	cases := []struct {
+		value   string
+		changed bool
+	}{
+		{value: "hunter2", changed: false},
+		{value: "hunter3", changed: true},
+	}
+
+	for _, c := range cases {
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"project_id":   "00000000-0000-0000-0000-000000000000",
+			"key":          "API_KEY",
+			"secret_value": c.value,
+		})
+		diff, err := resourceEnvVar().Diff(context.Background(), state, config, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.value, err)
+		}
+
+		changed := false
+		if diff != nil {
+			_, changed = diff.Attributes["secret_value"]
+		}
+		if changed != c.changed {
+			t.Errorf("%s: expected secret_value to change: %t, got %t", c.value, c.changed, changed)
+		}
+	}
+}
+
+func TestCustomizeDiffSecretEnvVars(t *testing.T) {
+	hash := hashSecretValue("hunter2")
+	state := &terraform.InstanceState{
+		ID: "00000000-0000-0000-0000-000000000000",
+		Attributes: map[string]string{
+			"id":                            "00000000-0000-0000-0000-000000000000",
+			"name":                          "terraform-test",
+			"default_domain":                "terraform-test.deno.dev",
+			"url":                           "https://terraform-test.deno.dev",
+			"secret_env_var.#":              "1",
+			"secret_env_var.1.key":          "API_KEY",
+			"secret_env_var.1.value":        "",
+			"secret_env_var_hashes.%":       "1",
+			"secret_env_var_hashes.API_KEY": hash,
+		},
+	}
+
+	cases := []struct {
+		name    string
+		vars    map[string]string
+		changed bool
+	}{
+		{name: "no change", vars: map[string]string{"API_KEY": "hunter2"}, changed: false},
+		{name: "value change", vars: map[string]string{"API_KEY": "hunter3"}, changed: true},
+		{name: "key added", vars: map[string]string{"API_KEY": "hunter2", "OTHER_KEY": "hunter2"}, changed: true},
+	}
+
+	for _, c := range cases {
+		blocks := []interface{}{}
+		values := []cty.Value{}
+		for k, v := range c.vars {
+			blocks = append(blocks, map[string]interface{}{"key": k, "value": v})
+			values = append(values, cty.ObjectVal(map[string]cty.Value{
+				"key":   cty.StringVal(k),
+				"value": cty.StringVal(v),
+			}))
+		}
+		state := state.DeepCopy()
+		state.RawConfig = cty.ObjectVal(map[string]cty.Value{
+			"secret_env_var": cty.SetVal(values),
+		})
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"name":           "terraform-test",
+			"secret_env_var": blocks,
+		})
+
+		diff, err := resourceProject().Diff(context.Background(), state, config, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+
+		changed := false
+		if diff != nil {
+			if attr, ok := diff.Attributes["secret_env_var_hashes.%"]; ok {
+				changed = attr.NewComputed
+			}
+		}
+		if changed != c.changed {
+			t.Errorf("%s: expected secret_env_var_hashes to change: %t, got %t", c.name, c.changed, changed)
+		}
+	}
+}
+
+func TestCustomizeDiffEnvVars_duplicateSecretKeys(t *testing.T) {
+	state := &terraform.InstanceState{
+		RawConfig: cty.ObjectVal(map[string]cty.Value{
+			"secret_env_var": cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal("API_KEY"), "value": cty.StringVal("hunter2")}),
+				cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal("API_KEY"), "value": cty.StringVal("hunter3")}),
+			}),
+		}),
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name": "terraform-test",
+		"secret_env_var": []interface{}{
+			map[string]interface{}{"key": "API_KEY", "value": "hunter2"},
+			map[string]interface{}{"key": "API_KEY", "value": "hunter3"},
+		},
+	})
+
+	_, err := resourceProject().Diff(context.Background(), state, config, nil)
+	if err == nil || !strings.Contains(err.Error(), `duplicate environment variable "API_KEY"`) {
+		t.Errorf("expected a duplicate environment variable error, got %v", err)
+	}
+}
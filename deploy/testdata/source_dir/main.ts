@@ -0,0 +1,5 @@
+import { greeting } from "./lib/greeting.ts";
+
+addEventListener("fetch", (event) => {
+  event.respondWith(new Response(greeting));
+});
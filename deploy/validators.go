@@ -0,0 +1,94 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	minProjectNameLength = 3
+	maxProjectNameLength = 26
+
+	// reservedEnvVarPrefix is the prefix of the environment variables set by
+	// Deploy itself, such as DENO_DEPLOYMENT_ID.
+	reservedEnvVarPrefix = "DENO_"
+
+	// maxEnvVarValueSize is the maximum size in bytes of the value of an
+	// environment variable.
+	maxEnvVarValueSize = 4 * 1024
+)
+
+var (
+	projectNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	envVarKeyRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// checkProjectName returns an error if the name is not a valid project name.
+// Project names are used as subdomains of deno.dev: they are made of 3 to 26
+// lowercase letters, digits and dashes, and can't start or end with a dash.
+func checkProjectName(name string) error {
+	if len(name) < minProjectNameLength || len(name) > maxProjectNameLength {
+		return fmt.Errorf("must be between %d and %d characters long", minProjectNameLength, maxProjectNameLength)
+	}
+	if !projectNameRegexp.MatchString(name) {
+		return fmt.Errorf("must only contain lowercase letters, digits and dashes, and can't start or end with a dash")
+	}
+	return nil
+}
+
+// checkEnvVarKey returns an error if the key is not a valid environment
+// variable name, or if it is reserved by Deploy.
+func checkEnvVarKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	if !envVarKeyRegexp.MatchString(key) {
+		return fmt.Errorf("must only contain letters, digits and underscores, and can't start with a digit")
+	}
+	if strings.HasPrefix(strings.ToUpper(key), reservedEnvVarPrefix) {
+		return fmt.Errorf("must not start with %s, which is reserved by Deploy", reservedEnvVarPrefix)
+	}
+	return nil
+}
+
+// checkEnvVarValue returns an error if the value of an environment variable is
+// too large.
+func checkEnvVarValue(value string) error {
+	if len(value) > maxEnvVarValueSize {
+		return fmt.Errorf("must be at most %d bytes long, got %d", maxEnvVarValueSize, len(value))
+	}
+	return nil
+}
+
+// validateProjectName is a SchemaValidateFunc checking that the value is a
+// valid project name.
+func validateProjectName(v interface{}, k string) ([]string, []error) {
+	return validateString(v, k, checkProjectName)
+}
+
+// validateEnvVarKey is a SchemaValidateFunc checking that the value is a valid
+// environment variable name.
+func validateEnvVarKey(v interface{}, k string) ([]string, []error) {
+	return validateString(v, k, checkEnvVarKey)
+}
+
+// validateEnvVarValue is a SchemaValidateFunc checking that the value is a
+// valid environment variable value.
+func validateEnvVarValue(v interface{}, k string) ([]string, []error) {
+	return validateString(v, k, checkEnvVarValue)
+}
+
+func validateString(v interface{}, k string, check func(string) error) ([]string, []error) {
+	value, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %q to be string", k)}
+	}
+
+	if err := check(value); err != nil {
+		return nil, []error{fmt.Errorf("%q: %w", k, err)}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Deno Land Inc. All rights reserved. MIT License.
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckProjectName(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"abc", true},
+		{"my-test-project", true},
+		{"terraform-test-a1b2", true},
+		{"0123456789", true},
+		{strings.Repeat("a", 26), true},
+		{"", false},
+		{"ab", false},
+		{strings.Repeat("a", 27), false},
+		{"My-Project", false},
+		{"-project", false},
+		{"project-", false},
+		{"my_project", false},
+		{"my.project", false},
+		{"my project", false},
+	}
+
+	for _, c := range cases {
+		err := checkProjectName(c.input)
+		if c.valid && err != nil {
+			t.Errorf("checkProjectName(%q): unexpected error: %s", c.input, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("checkProjectName(%q): expected an error", c.input)
+		}
+	}
+}
+
+func TestCheckEnvVarKey(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"FOO", true},
+		{"foo", true},
+		{"_FOO", true},
+		{"FOO_BAR_2", true},
+		{"DENOFOO", true},
+		{"MY_DENO_KEY", true},
+		{"", false},
+		{"2FOO", false},
+		{"FOO-BAR", false},
+		{"FOO BAR", false},
+		{"FOO=BAR", false},
+		{"DENO_DEPLOYMENT_ID", false},
+		{"deno_foo", false},
+	}
+
+	for _, c := range cases {
+		err := checkEnvVarKey(c.input)
+		if c.valid && err != nil {
+			t.Errorf("checkEnvVarKey(%q): unexpected error: %s", c.input, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("checkEnvVarKey(%q): expected an error", c.input)
+		}
+	}
+}
+
+func TestCheckEnvVarValue(t *testing.T) {
+	if err := checkEnvVarValue(""); err != nil {
+		t.Errorf("unexpected error for an empty value: %s", err)
+	}
+	if err := checkEnvVarValue(strings.Repeat("a", maxEnvVarValueSize)); err != nil {
+		t.Errorf("unexpected error for a value of the maximum size: %s", err)
+	}
+	if err := checkEnvVarValue(strings.Repeat("a", maxEnvVarValueSize+1)); err == nil {
+		t.Errorf("expected an error for a value larger than the maximum size")
+	}
+}